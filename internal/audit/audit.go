@@ -0,0 +1,330 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// KeyFileEnv moves the audit key out of ~/.nlcli, e.g. to a file only an
+// administrator can change.
+const KeyFileEnv = "NLCLI_AUDIT_KEY_FILE"
+
+var logPath string
+var keyPath string
+var headPath string
+
+func init() {
+	home, _ := os.UserHomeDir()
+	logPath = filepath.Join(home, ".nlcli", "audit.log")
+	keyPath = filepath.Join(home, ".nlcli", "audit.key")
+	headPath = filepath.Join(home, ".nlcli", "audit.head")
+}
+
+const (
+	ConfirmNotRequired = "not_required"
+	ConfirmAccepted    = "confirmed"
+	ConfirmDeclined    = "declined"
+	ConfirmBypassed    = "bypassed"
+)
+
+// Record is one REPL action. Each record carries the hash of the one before
+// it, so editing or deleting a line breaks the chain from that point on. The
+// hashes are HMACs keyed with the audit key, which is not in the log, so the
+// chain cannot be recomputed after an edit without it.
+type Record struct {
+	Time         time.Time `json:"time"`
+	Kind         string    `json:"kind"`
+	Input        string    `json:"input"`
+	Provider     string    `json:"provider,omitempty"`
+	Model        string    `json:"model,omitempty"`
+	Command      string    `json:"command,omitempty"`
+	Risk         string    `json:"risk,omitempty"`
+	Flags        []string  `json:"flags,omitempty"`
+	Confirmation string    `json:"confirmation,omitempty"`
+	ExitCode     *int      `json:"exit_code,omitempty"`
+	DurationMS   int64     `json:"duration_ms"`
+	Cwd          string    `json:"cwd"`
+	Error        string    `json:"error,omitempty"`
+	Prev         string    `json:"prev"`
+	Hash         string    `json:"hash"`
+}
+
+func Path() string {
+	return logPath
+}
+
+func (r *Record) SetExitCode(code int) {
+	r.ExitCode = &code
+}
+
+func (r Record) computeHash(key []byte) string {
+	r.Hash = ""
+	data, _ := json.Marshal(r)
+	return sign(key, data)
+}
+
+func sign(key, data []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// head records how many records the log holds and the hash of the last one.
+// It is kept in its own file and signed, so cutting records off the end of
+// the log, which leaves a valid chain behind, is still detected.
+type head struct {
+	Count int    `json:"count"`
+	Hash  string `json:"hash"`
+	MAC   string `json:"mac"`
+}
+
+func (h head) computeMAC(key []byte) string {
+	return sign(key, []byte(fmt.Sprintf("%d:%s", h.Count, h.Hash)))
+}
+
+// KeyPath returns where the audit key is kept: $NLCLI_AUDIT_KEY_FILE or
+// ~/.nlcli/audit.key.
+func KeyPath() string {
+	if path := os.Getenv(KeyFileEnv); path != "" {
+		return path
+	}
+	return keyPath
+}
+
+// loadKey reads the audit key, generating one when create is set and there
+// is none yet.
+func loadKey(create bool) ([]byte, error) {
+	data, err := os.ReadFile(KeyPath())
+	if os.IsNotExist(err) && create {
+		raw := make([]byte, 32)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(KeyPath()), 0700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(KeyPath(), []byte(hex.EncodeToString(raw)+"\n"), 0600); err != nil {
+			return nil, err
+		}
+		return raw, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("audit key: %w", err)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("audit key %s is malformed", KeyPath())
+	}
+	return key, nil
+}
+
+func readHead() (*head, error) {
+	data, err := os.ReadFile(headPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var h head
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("malformed audit head %s: %w", headPath, err)
+	}
+	return &h, nil
+}
+
+func writeHead(key []byte, count int, hash string) error {
+	h := head{Count: count, Hash: hash}
+	h.MAC = h.computeMAC(key)
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(headPath), ".audit.head-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), headPath)
+}
+
+// Append chains rec to the last record in the log, writes it and moves the
+// head forward.
+func Append(rec Record) error {
+	// sessions running at once must not chain to the same record or move the
+	// head back
+	unlock, err := lockLog()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	key, err := loadKey(true)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(logPath, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	prev, err := lastHash(f)
+	if err != nil {
+		return err
+	}
+	count := 0
+	if h, err := readHead(); err != nil {
+		return err
+	} else if h != nil {
+		count = h.Count
+	}
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+	rec.Time = rec.Time.UTC()
+	rec.Prev = prev
+	rec.Hash = rec.computeHash(key)
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return writeHead(key, count+1, rec.Hash)
+}
+
+// lockLog holds an exclusive lock on audit.log.lock until the returned
+// function is called. The head is replaced on every write, so the lock is
+// on a file of its own.
+func lockLog() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(logPath), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(logPath+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// lastHash reads the hash of the final record without loading the whole log.
+func lastHash(f *os.File) (string, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	size := info.Size()
+	if size == 0 {
+		return "", nil
+	}
+
+	chunk := int64(64 * 1024)
+	for {
+		if chunk > size {
+			chunk = size
+		}
+		buf := make([]byte, chunk)
+		if _, err := f.ReadAt(buf, size-chunk); err != nil && err != io.EOF {
+			return "", err
+		}
+		buf = bytes.TrimRight(buf, "\n")
+		if i := bytes.LastIndexByte(buf, '\n'); i >= 0 || chunk == size {
+			var rec Record
+			if err := json.Unmarshal(buf[i+1:], &rec); err != nil {
+				return "", fmt.Errorf("audit log ends with a malformed record: %w", err)
+			}
+			return rec.Hash, nil
+		}
+		chunk *= 2
+	}
+}
+
+// Verify walks the log and checks every record's hash and its link to the
+// previous record, then that the log ends where the head says it does. It
+// returns the number of valid records before the first problem.
+func Verify() (int, error) {
+	key, err := loadKey(false)
+	if err != nil {
+		return 0, err
+	}
+	h, err := readHead()
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.Open(logPath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	prev := ""
+	n := 0
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(data)) > 0 {
+			var rec Record
+			if jsonErr := json.Unmarshal(data, &rec); jsonErr != nil {
+				return n, fmt.Errorf("line %d: malformed record: %w", line, jsonErr)
+			}
+			if rec.Prev != prev {
+				return n, fmt.Errorf("line %d: chain broken (previous record missing or altered)", line)
+			}
+			if rec.computeHash(key) != rec.Hash {
+				return n, fmt.Errorf("line %d: record has been modified", line)
+			}
+			prev = rec.Hash
+			n++
+		}
+		if err == io.EOF {
+			return n, checkHead(key, h, n, prev)
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// checkHead compares the end of the log with the signed head.
+func checkHead(key []byte, h *head, n int, last string) error {
+	if h == nil {
+		if n > 0 {
+			return fmt.Errorf("head file %s is missing", headPath)
+		}
+		return nil
+	}
+	if !hmac.Equal([]byte(h.computeMAC(key)), []byte(h.MAC)) {
+		return fmt.Errorf("head file %s has been modified", headPath)
+	}
+	if h.Count != n || h.Hash != last {
+		return fmt.Errorf("log ends after %d records but %d were written (truncated or rolled back)", n, h.Count)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAppendAndVerify(t *testing.T) {
+	dir := t.TempDir()
+	logPath = filepath.Join(dir, "audit.log")
+	keyPath = filepath.Join(dir, "audit.key")
+	headPath = filepath.Join(dir, "audit.head")
+	t.Setenv(KeyFileEnv, "")
+
+	for _, input := range []string{"list files", "delete the logs", "show disk usage"} {
+		rec := Record{Kind: "translate", Input: input, Command: "ls", Risk: "none"}
+		rec.SetExitCode(0)
+		if err := Append(rec); err != nil {
+			t.Fatalf("Append() error: %v", err)
+		}
+	}
+
+	if n, err := Verify(); err != nil || n != 3 {
+		t.Fatalf("Verify() = %d, %v, want 3, nil", n, err)
+	}
+
+	data, _ := os.ReadFile(logPath)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	tests := []struct {
+		name  string
+		log   string
+		wantN int
+	}{
+		{name: "edit", log: strings.Replace(string(data), "delete the logs", "delete the cache", 1), wantN: 1},
+		{name: "deletion", log: lines[0] + "\n" + lines[2] + "\n", wantN: 1},
+		{name: "truncation", log: lines[0] + "\n" + lines[1] + "\n", wantN: 2},
+		{name: "emptied", log: "", wantN: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.WriteFile(logPath, []byte(tt.log), 0600)
+			if n, err := Verify(); err == nil || n != tt.wantN {
+				t.Errorf("Verify() = %d, %v, want %d and an error", n, err, tt.wantN)
+			}
+		})
+	}
+}
+
+func TestConcurrentAppend(t *testing.T) {
+	dir := t.TempDir()
+	logPath = filepath.Join(dir, "audit.log")
+	keyPath = filepath.Join(dir, "audit.key")
+	headPath = filepath.Join(dir, "audit.head")
+	t.Setenv(KeyFileEnv, "")
+
+	const sessions, each = 8, 10
+	var wg sync.WaitGroup
+	for range sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range each {
+				if err := Append(Record{Kind: "special", Input: ".help"}); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n, err := Verify(); err != nil || n != sessions*each {
+		t.Errorf("Verify() = %d, %v, want %d, nil", n, err, sessions*each)
+	}
+}
+
+func TestVerifyNeedsKey(t *testing.T) {
+	dir := t.TempDir()
+	logPath = filepath.Join(dir, "audit.log")
+	keyPath = filepath.Join(dir, "audit.key")
+	headPath = filepath.Join(dir, "audit.head")
+	t.Setenv(KeyFileEnv, "")
+
+	if err := Append(Record{Kind: "special", Input: ".help"}); err != nil {
+		t.Fatal(err)
+	}
+	// a record re-signed with another key does not verify
+	os.WriteFile(keyPath, []byte(strings.Repeat("ab", 32)+"\n"), 0600)
+	if _, err := Verify(); err == nil {
+		t.Error("Verify() with a different key succeeded")
+	}
+	os.Remove(keyPath)
+	if _, err := Verify(); err == nil {
+		t.Error("Verify() without the key succeeded")
+	}
+}
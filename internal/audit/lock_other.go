@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package audit
+
+import "os"
+
+// lockFile does nothing where file locks are not available; sessions
+// appending at once may then break the chain.
+func lockFile(f *os.File) error {
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/markymn/nlcli/internal/usage"
+)
+
+func BudgetPath() string {
+	return filepath.Join(configDir, "budget.toml")
+}
+
+// LoadBudget reads the spending limits. A missing file means no limits.
+func LoadBudget() (*usage.Budget, error) {
+	path := BudgetPath()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	b, err := ParseBudget(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	b.Path = path
+	return b, nil
+}
+
+// ParseBudget reads a budget file:
+//
+//	daily_cost = 1.50            # USD
+//	monthly_cost = 20
+//	daily_tokens = 200000
+//	monthly_tokens = 5000000
+//	requests_per_minute = 10
+//	action = "downgrade"         # warn, downgrade or refuse
+//	cheap_model = "gpt-4o-mini"  # for downgrade; default is the cheapest known
+func ParseBudget(data string) (*usage.Budget, error) {
+	fields, err := parseFlatTOML(data)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &usage.Budget{}
+	for _, f := range fields {
+		if f.Array {
+			return nil, f.errorf("%s does not take an array", f.Key)
+		}
+		value := f.Value()
+		switch f.Key {
+		case "daily_cost", "monthly_cost":
+			cost, err := strconv.ParseFloat(value, 64)
+			if err != nil || cost < 0 {
+				return nil, f.errorf("%s must be an amount in USD", f.Key)
+			}
+			if f.Key == "daily_cost" {
+				b.DailyCost = cost
+			} else {
+				b.MonthlyCost = cost
+			}
+		case "daily_tokens", "monthly_tokens":
+			tokens, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || tokens < 0 {
+				return nil, f.errorf("%s must be a number of tokens", f.Key)
+			}
+			if f.Key == "daily_tokens" {
+				b.DailyTokens = tokens
+			} else {
+				b.MonthlyTokens = tokens
+			}
+		case "requests_per_minute":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, f.errorf("requests_per_minute must be a whole number")
+			}
+			b.RequestsPerMinute = n
+		case "action":
+			action, ok := usage.ParseAction(value)
+			if !ok {
+				return nil, f.errorf("action must be warn, downgrade or refuse")
+			}
+			b.Action = action
+		case "cheap_model":
+			b.CheapModel = value
+		default:
+			return nil, f.errorf("unknown key %q", f.Key)
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/markymn/nlcli/internal/usage"
+)
+
+func TestParseBudget(t *testing.T) {
+	b, err := ParseBudget(`
+daily_cost = 1.50
+monthly_tokens = 5000000
+requests_per_minute = 10
+action = "downgrade"
+`)
+	if err != nil {
+		t.Fatalf("ParseBudget() error = %v", err)
+	}
+	want := usage.Budget{DailyCost: 1.5, MonthlyTokens: 5000000, RequestsPerMinute: 10, Action: usage.ActionDowngrade}
+	if *b != want {
+		t.Errorf("ParseBudget() = %+v, want %+v", *b, want)
+	}
+
+	for _, bad := range []string{`daily_cost = lots`, `action = "panic"`, `requests_per_minute = -1`, `weekly_cost = 5`} {
+		if _, err := ParseBudget(bad); err == nil {
+			t.Errorf("ParseBudget(%q) succeeded, want error", bad)
+		}
+	}
+}
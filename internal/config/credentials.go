@@ -0,0 +1,188 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/markymn/nlcli/internal/vault"
+)
+
+const apiKeyCredential = "API_KEY"
+
+var vaultPrompt vault.Prompt
+
+// SetVaultPrompt sets how the vault passphrase is asked for. Until it is set
+// the vault only opens with a key file.
+func SetVaultPrompt(p vault.Prompt) {
+	vaultPrompt = p
+}
+
+// credentialsInVault reports whether API keys live in the encrypted vault
+// rather than in .env.
+func credentialsInVault() bool {
+	value, err := loadValue("CREDENTIAL_STORE")
+	return err == nil && value == "vault"
+}
+
+// CredentialStore describes where the API key is kept, for display.
+func CredentialStore() string {
+	if credentialsInVault() {
+		return "vault"
+	}
+	return "plaintext"
+}
+
+// HasPlaintextAPIKey reports whether .env still holds an API key.
+func HasPlaintextAPIKey() bool {
+	return len(plaintextCredentials()) > 0
+}
+
+// plaintextCredentials lists the API key entries in .env.
+func plaintextCredentials() []string {
+	data, err := os.ReadFile(envPath)
+	if err != nil {
+		return nil
+	}
+	var keys []string
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, _ := strings.Cut(strings.TrimSpace(line), "=")
+		if value != "" && (key == apiKeyCredential || strings.HasPrefix(key, apiKeyCredential+"_")) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// providerKey is the .env entry and vault credential holding the API key of
+// the named provider.
+func providerKey(name string) string {
+	return apiKeyCredential + "_" + strings.ToUpper(name)
+}
+
+// loadCredential reads a stored API key. A missing key is not an error, but a
+// vault that cannot be unlocked is.
+func loadCredential(key string) (string, bool, error) {
+	if !credentialsInVault() {
+		value, err := loadValue(key)
+		return value, err == nil && value != "", nil
+	}
+	v, err := vault.Unlock(vaultPrompt)
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := v.Credential(key)
+	return value, ok && value != "", nil
+}
+
+func saveCredential(key, value string) error {
+	if !credentialsInVault() {
+		return saveValues(map[string]string{key: value}, key)
+	}
+	v, err := vault.Unlock(vaultPrompt)
+	if err != nil {
+		return err
+	}
+	v.SetCredential(key, value)
+	if err := v.Save(); err != nil {
+		return err
+	}
+	return removeValues(key)
+}
+
+func deleteCredential(key string) error {
+	if !credentialsInVault() {
+		return removeValues(key)
+	}
+	v, err := vault.Unlock(vaultPrompt)
+	if err != nil {
+		return err
+	}
+	v.DeleteCredential(key)
+	return v.Save()
+}
+
+// LoadProviderKey returns the API key stored for the named provider. The
+// single API_KEY of older versions belongs to the active provider.
+func LoadProviderKey(name string) (string, error) {
+	key, ok, err := loadCredential(providerKey(name))
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		if active, _ := LoadProvider(); active == name {
+			if key, ok, err = loadCredential(apiKeyCredential); err != nil {
+				return "", err
+			}
+		}
+	}
+	if !ok {
+		return "", fmt.Errorf("no API key stored for %s", name)
+	}
+	return key, nil
+}
+
+// SaveProviderKey stores the API key for the named provider, replacing the
+// older single API_KEY if it belonged to the same provider.
+func SaveProviderKey(name, key string) error {
+	if err := saveCredential(providerKey(name), key); err != nil {
+		return err
+	}
+	if active, _ := LoadProvider(); active == name {
+		return deleteCredential(apiKeyCredential)
+	}
+	return nil
+}
+
+// StoredProviders lists the providers an API key is stored for.
+func StoredProviders() ([]string, error) {
+	keys := plaintextCredentials()
+	if credentialsInVault() {
+		v, err := vault.Unlock(vaultPrompt)
+		if err != nil {
+			return nil, err
+		}
+		keys = v.CredentialNames()
+	}
+
+	var names []string
+	for _, key := range keys {
+		name := ""
+		if key == apiKeyCredential {
+			name, _ = LoadProvider()
+		} else {
+			name = strings.ToLower(strings.TrimPrefix(key, apiKeyCredential+"_"))
+		}
+		if name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// MigrateToVault moves the plaintext API keys from .env into the vault and
+// switches credential storage over to it.
+func MigrateToVault() error {
+	keys := plaintextCredentials()
+	if len(keys) == 0 {
+		return fmt.Errorf("no plaintext API key in %s", envPath)
+	}
+	v, err := vault.Unlock(vaultPrompt)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		value, _ := loadValue(key)
+		v.SetCredential(key, value)
+	}
+	if err := v.Save(); err != nil {
+		return err
+	}
+	if err := saveValues(map[string]string{"CREDENTIAL_STORE": "vault"}, "CREDENTIAL_STORE"); err != nil {
+		return err
+	}
+	return removeValues(keys...)
+}
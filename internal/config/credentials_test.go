@@ -0,0 +1,56 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestProviderKeys(t *testing.T) {
+	dir := t.TempDir()
+	oldDir, oldPath := configDir, envPath
+	configDir, envPath = dir, filepath.Join(dir, ".env")
+	defer func() { configDir, envPath = oldDir, oldPath }()
+
+	// a key saved by an older version, before keys were kept per provider
+	os.WriteFile(envPath, []byte("API_KEY=sk-old\nMODEL=gpt-4o\nPROVIDER=openai\n"), 0600)
+
+	if key, err := LoadProviderKey("openai"); err != nil || key != "sk-old" {
+		t.Errorf("LoadProviderKey(openai) = %q, %v; want the legacy key", key, err)
+	}
+	if _, err := LoadProviderKey("anthropic"); err == nil {
+		t.Error("LoadProviderKey(anthropic) found a key that belongs to openai")
+	}
+
+	if err := SaveProviderKey("anthropic", "sk-ant-1"); err != nil {
+		t.Fatal(err)
+	}
+	SaveProviderModel("anthropic", "claude-3-5-haiku-20241022")
+	if err := SaveProviderKey("openai", "sk-new"); err != nil {
+		t.Fatal(err)
+	}
+
+	if key, _ := LoadProviderKey("openai"); key != "sk-new" {
+		t.Errorf("LoadProviderKey(openai) = %q, want sk-new", key)
+	}
+	if _, err := loadValue("API_KEY"); err == nil {
+		t.Error("legacy API_KEY was kept after openai got its own key")
+	}
+	if model, _ := LoadModel(); model != "gpt-4o" {
+		t.Errorf("LoadModel() = %q, want the legacy MODEL", model)
+	}
+
+	SaveProvider("anthropic")
+	if key, _ := LoadAPIKey(); key != "sk-ant-1" {
+		t.Errorf("LoadAPIKey() = %q after switching to anthropic", key)
+	}
+	if model, _ := LoadModel(); model != "claude-3-5-haiku-20241022" {
+		t.Errorf("LoadModel() = %q after switching to anthropic", model)
+	}
+
+	names, err := StoredProviders()
+	if err != nil || !slices.Equal(names, []string{"anthropic", "openai"}) {
+		t.Errorf("StoredProviders() = %v, %v", names, err)
+	}
+}
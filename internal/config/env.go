@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -20,7 +21,7 @@ func init() {
 	envPath = filepath.Join(configDir, ".env")
 }
 
-func LoadAPIKey() (string, error) {
+func loadValue(key string) (string, error) {
 	data, err := os.ReadFile(envPath)
 	if err != nil {
 		return "", err
@@ -28,70 +29,259 @@ func LoadAPIKey() (string, error) {
 
 	for _, line := range strings.Split(string(data), "\n") {
 		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "API_KEY=") {
-			return strings.TrimPrefix(line, "API_KEY="), nil
+		if strings.HasPrefix(line, key+"=") {
+			return strings.TrimPrefix(line, key+"="), nil
 		}
 	}
-	return "", fmt.Errorf("API_KEY not found")
+	return "", fmt.Errorf("%s not found", key)
 }
 
-func LoadModel() (string, error) {
+// saveValues updates the given keys in .env, keeping any other settings.
+func saveValues(values map[string]string, order ...string) error {
+	if err := os.MkdirAll(configDir, 0700); err != nil {
+		return err
+	}
+
+	var lines []string
+	written := make(map[string]bool)
+	if data, err := os.ReadFile(envPath); err == nil {
+		for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+			key, _, _ := strings.Cut(strings.TrimSpace(line), "=")
+			if value, ok := values[key]; ok {
+				if !written[key] {
+					lines = append(lines, key+"="+value)
+					written[key] = true
+				}
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+
+	for _, key := range order {
+		if !written[key] {
+			lines = append(lines, key+"="+values[key])
+		}
+	}
+	return os.WriteFile(envPath, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+}
+
+// removeValues deletes keys from .env, keeping everything else.
+func removeValues(keys ...string) error {
 	data, err := os.ReadFile(envPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	for _, line := range strings.Split(string(data), "\n") {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "MODEL=") {
-			return strings.TrimPrefix(line, "MODEL="), nil
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		key, _, _ := strings.Cut(strings.TrimSpace(line), "=")
+		if line != "" && !slices.Contains(keys, key) {
+			lines = append(lines, line)
 		}
 	}
-	return "", fmt.Errorf("MODEL not found")
+	return os.WriteFile(envPath, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+}
+
+// LoadAPIKey returns the API key of the active provider.
+func LoadAPIKey() (string, error) {
+	if name, _ := LoadProvider(); name != "" {
+		return LoadProviderKey(name)
+	}
+	key, ok, err := loadCredential(apiKeyCredential)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("API_KEY not found")
+	}
+	return key, nil
+}
+
+// LoadProvider returns the active provider, picked in .api or .use, which
+// may not be guessable from the key itself.
+func LoadProvider() (string, error) {
+	return loadValue("PROVIDER")
+}
+
+func SaveProvider(name string) error {
+	return saveValues(map[string]string{"PROVIDER": name}, "PROVIDER")
+}
+
+// LoadModel returns the model of the active provider.
+func LoadModel() (string, error) {
+	if name, _ := LoadProvider(); name != "" {
+		return LoadProviderModel(name)
+	}
+	return loadValue("MODEL")
+}
+
+// LoadProviderModel returns the model last used with the named provider.
+func LoadProviderModel(name string) (string, error) {
+	if model, err := loadValue(providerModelKey(name)); err == nil {
+		return model, nil
+	}
+	if active, _ := LoadProvider(); active == name {
+		return loadValue("MODEL")
+	}
+	return "", fmt.Errorf("no model stored for %s", name)
+}
+
+func SaveProviderModel(name, model string) error {
+	key := providerModelKey(name)
+	return saveValues(map[string]string{key: model}, key)
+}
+
+func providerModelKey(name string) string {
+	return "MODEL_" + strings.ToUpper(name)
 }
 
 func LoadSafetyLevel() int {
-	data, err := os.ReadFile(envPath)
+	levelStr, err := loadValue("SAFETY_LEVEL")
 	if err != nil {
 		return 1
 	}
-
-	for _, line := range strings.Split(string(data), "\n") {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "SAFETY_LEVEL=") {
-			levelStr := strings.TrimPrefix(line, "SAFETY_LEVEL=")
-			if level, err := strconv.Atoi(levelStr); err == nil {
-				return level
-			}
-		}
+	if level, err := strconv.Atoi(levelStr); err == nil {
+		return level
 	}
 	return 1
 }
 
+// SaveConfig stores key and model for the active provider.
 func SaveConfig(key, model string, safety int) error {
-	if err := os.MkdirAll(configDir, 0700); err != nil {
+	if err := SaveAPIKey(key); err != nil {
 		return err
 	}
-	content := fmt.Sprintf("API_KEY=%s\nMODEL=%s\nSAFETY_LEVEL=%d\n", key, model, safety)
-	return os.WriteFile(envPath, []byte(content), 0600)
+	if err := SaveModel(model); err != nil {
+		return err
+	}
+	return SaveSafetyLevel(safety)
 }
 
+// SaveAPIKey stores key for the active provider, in the vault once
+// credentials have been moved there and in .env otherwise.
 func SaveAPIKey(key string) error {
-	model, _ := LoadModel()
-	safety := LoadSafetyLevel()
-	return SaveConfig(key, model, safety)
+	if name, _ := LoadProvider(); name != "" {
+		return SaveProviderKey(name, key)
+	}
+	return saveCredential(apiKeyCredential, key)
 }
 
 func SaveModel(model string) error {
-	key, _ := LoadAPIKey()
-	safety := LoadSafetyLevel()
-	return SaveConfig(key, model, safety)
+	if name, _ := LoadProvider(); name != "" {
+		return SaveProviderModel(name, model)
+	}
+	return saveValues(map[string]string{"MODEL": model}, "MODEL")
 }
 
 func SaveSafetyLevel(level int) error {
-	key, _ := LoadAPIKey()
-	model, _ := LoadModel()
-	return SaveConfig(key, model, level)
+	return saveValues(map[string]string{"SAFETY_LEVEL": strconv.Itoa(level)}, "SAFETY_LEVEL")
+}
+
+func LoadTrashMode() bool {
+	value, err := loadValue("TRASH_MODE")
+	return err == nil && value == "1"
+}
+
+func SaveTrashMode(enabled bool) error {
+	value := "0"
+	if enabled {
+		value = "1"
+	}
+	return saveValues(map[string]string{"TRASH_MODE": value}, "TRASH_MODE")
+}
+
+// LoadForbidElevation reports whether translated commands may not use sudo,
+// doas, su, pkexec or RunAs.
+func LoadForbidElevation() bool {
+	value, err := loadValue("FORBID_ELEVATION")
+	return err == nil && value == "1"
+}
+
+func SaveForbidElevation(forbid bool) error {
+	value := "0"
+	if forbid {
+		value = "1"
+	}
+	return saveValues(map[string]string{"FORBID_ELEVATION": value}, "FORBID_ELEVATION")
+}
+
+// LoadExplain reports whether the model's reasoning is shown with each
+// command.
+func LoadExplain() bool {
+	value, err := loadValue("EXPLAIN")
+	return err == nil && value == "1"
+}
+
+func SaveExplain(explain bool) error {
+	value := "0"
+	if explain {
+		value = "1"
+	}
+	return saveValues(map[string]string{"EXPLAIN": value}, "EXPLAIN")
+}
+
+// LoadVerifierModel returns the model that reviews translated commands, or ""
+// when review is off.
+func LoadVerifierModel() string {
+	value, _ := loadValue("VERIFIER_MODEL")
+	return value
+}
+
+func SaveVerifierModel(model string) error {
+	return saveValues(map[string]string{"VERIFIER_MODEL": model}, "VERIFIER_MODEL")
+}
+
+// LoadFallbackChain returns the provider/model entries tried in order when
+// the active provider fails.
+func LoadFallbackChain() []string {
+	value, err := loadValue("FALLBACK_CHAIN")
+	if err != nil || value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+func SaveFallbackChain(entries []string) error {
+	return saveValues(map[string]string{"FALLBACK_CHAIN": strings.Join(entries, ",")}, "FALLBACK_CHAIN")
+}
+
+// LoadStrategy returns how the fallback chain is used: "fallback", "race"
+// or "quorum".
+func LoadStrategy() string {
+	value, _ := loadValue("STRATEGY")
+	return value
+}
+
+func SaveStrategy(strategy string) error {
+	return saveValues(map[string]string{"STRATEGY": strategy}, "STRATEGY")
+}
+
+// LoadDirectPolicy returns the lowest safety level at which directly typed
+// commands are also checked, or 0 when they never are. Defaults to Cautious.
+func LoadDirectPolicy() int {
+	value, err := loadValue("DIRECT_POLICY")
+	if err != nil {
+		return 3
+	}
+	if level, err := strconv.Atoi(value); err == nil {
+		return level
+	}
+	return 3
+}
+
+func SaveDirectPolicy(level int) error {
+	return saveValues(map[string]string{"DIRECT_POLICY": strconv.Itoa(level)}, "DIRECT_POLICY")
+}
+
+func LoadAuditLog() bool {
+	value, err := loadValue("AUDIT_LOG")
+	return err != nil || value != "0"
 }
 
 func SetupAPIKey() (string, error) {
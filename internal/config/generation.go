@@ -0,0 +1,200 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Params are generation settings. Unset fields keep the provider's default.
+type Params struct {
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   int
+	Stop        []string
+	Seed        *int64
+	// ThinkingBudget is the token budget for extended thinking on models
+	// that support it; 0 turns thinking off where that is possible.
+	ThinkingBudget *int
+	// ReasoningEffort is "minimal", "low", "medium" or "high" for models
+	// that take a reasoning effort instead of a budget.
+	ReasoningEffort string
+}
+
+// merge overrides p with the fields set in o.
+func (p *Params) merge(o Params) {
+	if o.Temperature != nil {
+		p.Temperature = o.Temperature
+	}
+	if o.TopP != nil {
+		p.TopP = o.TopP
+	}
+	if o.MaxTokens != 0 {
+		p.MaxTokens = o.MaxTokens
+	}
+	if o.Stop != nil {
+		p.Stop = o.Stop
+	}
+	if o.Seed != nil {
+		p.Seed = o.Seed
+	}
+	if o.ThinkingBudget != nil {
+		p.ThinkingBudget = o.ThinkingBudget
+	}
+	if o.ReasoningEffort != "" {
+		p.ReasoningEffort = o.ReasoningEffort
+	}
+}
+
+// Generation holds params for every provider, for each provider, and for
+// each provider/model; the most specific wins.
+type Generation struct {
+	Path   string
+	scopes map[string]Params
+}
+
+// For returns the params for model of provider. It is safe on a nil
+// Generation.
+func (g *Generation) For(provider, model string) Params {
+	var p Params
+	if g == nil {
+		return p
+	}
+	for _, scope := range []string{"", provider, strings.ToLower(provider + "/" + model)} {
+		p.merge(g.scopes[scope])
+	}
+	return p
+}
+
+func GenerationPath() string {
+	return filepath.Join(configDir, "generation.toml")
+}
+
+// LoadGeneration reads GenerationPath. A missing file means no settings.
+func LoadGeneration() (*Generation, error) {
+	path := GenerationPath()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	g, err := ParseGeneration(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	g.Path = path
+	return g, nil
+}
+
+// ParseGeneration reads settings, optionally scoped to a provider or to a
+// provider/model, which needs quotes when the model has dots in it:
+//
+//	max_tokens = 1024
+//	openai.temperature = 0.2
+//	"google/gemini-2.5-flash".thinking_budget = 0
+//	anthropic.stop = ["\n\n"]
+//	openai.reasoning_effort = "medium"
+//
+// The keys are temperature, top_p, max_tokens, stop, seed, thinking_budget
+// and reasoning_effort.
+func ParseGeneration(data string) (*Generation, error) {
+	fields, err := parseFlatTOML(data)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Generation{scopes: make(map[string]Params)}
+	for _, f := range fields {
+		scope, key, err := splitScopedKey(f.Key)
+		if err != nil {
+			return nil, f.errorf("%s", err)
+		}
+		p := g.scopes[scope]
+		if key == "stop" {
+			if !f.Array {
+				return nil, f.errorf("stop takes an array of strings")
+			}
+			p.Stop = unescapeAll(f.Values)
+			g.scopes[scope] = p
+			continue
+		}
+		if f.Array {
+			return nil, f.errorf("%s does not take an array", key)
+		}
+
+		value := f.Value()
+		switch key {
+		case "temperature", "top_p":
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil || v < 0 {
+				return nil, f.errorf("%s must be a number", key)
+			}
+			if key == "temperature" {
+				p.Temperature = &v
+			} else {
+				p.TopP = &v
+			}
+		case "max_tokens", "thinking_budget":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, f.errorf("%s must be a number of tokens", key)
+			}
+			if key == "max_tokens" {
+				p.MaxTokens = n
+			} else {
+				p.ThinkingBudget = &n
+			}
+		case "reasoning_effort":
+			switch effort := strings.ToLower(value); effort {
+			case "minimal", "low", "medium", "high":
+				p.ReasoningEffort = effort
+			default:
+				return nil, f.errorf("reasoning_effort must be minimal, low, medium or high")
+			}
+		case "seed":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, f.errorf("seed must be a whole number")
+			}
+			p.Seed = &n
+		default:
+			return nil, f.errorf("unknown key %q", key)
+		}
+		g.scopes[scope] = p
+	}
+	return g, nil
+}
+
+// splitScopedKey splits `scope.key` into its parts. The scope is "" for
+// settings that apply everywhere.
+func splitScopedKey(s string) (scope, key string, err error) {
+	if strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "'") {
+		end := strings.IndexByte(s[1:], s[0])
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated quoted key")
+		}
+		scope, rest := s[1:end+1], s[end+2:]
+		if !strings.HasPrefix(rest, ".") {
+			return "", "", fmt.Errorf("expected .key after %q", scope)
+		}
+		return strings.ToLower(scope), rest[1:], nil
+	}
+	if i := strings.LastIndexByte(s, '.'); i >= 0 {
+		return strings.ToLower(s[:i]), s[i+1:], nil
+	}
+	return "", s, nil
+}
+
+// unescapeAll turns \n and \t in stop sequences into the characters.
+func unescapeAll(values []string) []string {
+	r := strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\\`, `\`)
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = r.Replace(v)
+	}
+	return out
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseGeneration(t *testing.T) {
+	g, err := ParseGeneration(`
+max_tokens = 1024
+temperature = 0.2
+openai.temperature = 0
+"google/gemini-2.5-flash".thinking_budget = 0
+"openai/gpt-4o".max_tokens = 2000
+anthropic.stop = ["\n\n", "END"]
+seed = 42
+openai.reasoning_effort = "Medium"
+`)
+	if err != nil {
+		t.Fatalf("ParseGeneration() error = %v", err)
+	}
+
+	p := g.For("openai", "gpt-4o")
+	if p.MaxTokens != 2000 || *p.Temperature != 0 || *p.Seed != 42 || p.ReasoningEffort != "medium" {
+		t.Errorf("openai/gpt-4o = %+v", p)
+	}
+	if p := g.For("openai", "gpt-4o-mini"); p.MaxTokens != 1024 || *p.Temperature != 0 {
+		t.Errorf("openai/gpt-4o-mini = %+v", p)
+	}
+	if p := g.For("groq", "llama-3.1-8b-instant"); *p.Temperature != 0.2 || p.ThinkingBudget != nil {
+		t.Errorf("groq = %+v", p)
+	}
+	if p := g.For("google", "gemini-2.5-flash"); p.ThinkingBudget == nil || *p.ThinkingBudget != 0 {
+		t.Errorf("google/gemini-2.5-flash thinking budget = %v, want 0", p.ThinkingBudget)
+	}
+	if p := g.For("anthropic", "claude-sonnet-4-20250514"); !slices.Equal(p.Stop, []string{"\n\n", "END"}) {
+		t.Errorf("anthropic stop = %q", p.Stop)
+	}
+
+	var none *Generation
+	if p := none.For("openai", "gpt-4o"); p.MaxTokens != 0 || p.Temperature != nil {
+		t.Errorf("nil Generation = %+v", p)
+	}
+
+	for _, bad := range []string{`temperature = hot`, `openai.stop = "END"`, `openai.color = "red"`, `"google/gemini-2.5-flash"max_tokens = 5`, `reasoning_effort = "max"`} {
+		if _, err := ParseGeneration(bad); err == nil {
+			t.Errorf("ParseGeneration(%q) succeeded, want error", bad)
+		}
+	}
+}
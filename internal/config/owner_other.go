@@ -0,0 +1,11 @@
+//go:build !unix
+
+package config
+
+import "os"
+
+// trustedFile always trusts the file where ownership and permission bits
+// are not available to check.
+func trustedFile(info os.FileInfo) bool {
+	return true
+}
@@ -0,0 +1,22 @@
+//go:build unix
+
+package config
+
+import (
+	"os"
+	"syscall"
+)
+
+// trustedFile reports whether only the current user or root can have
+// written the file described by info: it is owned by one of them and is
+// neither group- nor world-writable.
+func trustedFile(info os.FileInfo) bool {
+	if info.Mode().Perm()&0o022 != 0 {
+		return false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+	return int(st.Uid) == os.Getuid() || st.Uid == 0
+}
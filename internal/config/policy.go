@@ -0,0 +1,173 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/markymn/nlcli/internal/shell"
+)
+
+// policyPath, when set at build time with
+// -ldflags "-X github.com/markymn/nlcli/internal/config.policyPath=...",
+// moves the admin policy. There is deliberately no environment override:
+// users must not be able to point nlcli at no policy.
+var policyPath string
+
+// Policy is the system-wide configuration an administrator sets. User
+// settings and directory profiles can tighten it but never go below it.
+type Policy struct {
+	Path             string
+	MinSafety        shell.SafetyLevel
+	Deny             []string
+	AllowedProviders []string
+	AllowedEndpoints []string
+	RequireAudit     bool
+	ForbidElevation  bool
+}
+
+func PolicyPath() string {
+	if policyPath != "" {
+		return policyPath
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("ProgramData"), "nlcli", "policy")
+	}
+	return "/etc/nlcli/policy"
+}
+
+// LoadPolicy reads the admin policy. A missing file means no policy; a file
+// that exists but cannot be read or parsed is an error, so a broken policy
+// never silently turns into no policy.
+func LoadPolicy() (*Policy, error) {
+	path := PolicyPath()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	p, err := ParsePolicy(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	p.Path = path
+	return p, nil
+}
+
+// ParsePolicy reads a policy file:
+//
+//	min_safety = "cautious"
+//	deny = ["rm -rf /*", "* | sh"]
+//	allowed_providers = ["openai", "anthropic"]
+//	allowed_endpoints = ["https://api.openai.com"]
+//	require_audit = true
+//	forbid_elevation = true
+func ParsePolicy(data string) (*Policy, error) {
+	fields, err := parseFlatTOML(data)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Policy{}
+	for _, f := range fields {
+		switch f.Key {
+		case "min_safety":
+			level, ok := shell.ParseSafetyLevel(f.Value())
+			if f.Array || !ok {
+				return nil, f.errorf("unknown safety level %q", f.Value())
+			}
+			p.MinSafety = level
+		case "deny":
+			p.Deny = append(p.Deny, f.Values...)
+		case "allowed_providers":
+			for _, v := range f.Values {
+				p.AllowedProviders = append(p.AllowedProviders, strings.ToLower(v))
+			}
+		case "allowed_endpoints":
+			p.AllowedEndpoints = append(p.AllowedEndpoints, f.Values...)
+		case "require_audit", "forbid_elevation":
+			b, err := strconv.ParseBool(f.Value())
+			if f.Array || err != nil {
+				return nil, f.errorf("%s must be true or false", f.Key)
+			}
+			if f.Key == "require_audit" {
+				p.RequireAudit = b
+			} else {
+				p.ForbidElevation = b
+			}
+		default:
+			return nil, f.errorf("unknown key %q", f.Key)
+		}
+	}
+	return p, nil
+}
+
+// Denied returns the first deny rule matching cmd. Rules are matched against
+// the whole command with * standing for any run of characters, and against
+// each of its pipeline and list segments.
+func (p *Policy) Denied(cmd string) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	candidates := append([]string{cmd}, shell.Segments(cmd)...)
+	for _, rule := range p.Deny {
+		for _, c := range candidates {
+			if matchWildcard(strings.ToLower(strings.Join(strings.Fields(rule), " ")),
+				strings.ToLower(strings.Join(strings.Fields(c), " "))) {
+				return rule, true
+			}
+		}
+	}
+	return "", false
+}
+
+// AllowsProvider reports whether name (e.g. "openai") may be used.
+func (p *Policy) AllowsProvider(name string) bool {
+	if p == nil || len(p.AllowedProviders) == 0 {
+		return true
+	}
+	for _, a := range p.AllowedProviders {
+		if a == strings.ToLower(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsEndpoint reports whether url starts with one of the allowed endpoints.
+func (p *Policy) AllowsEndpoint(url string) bool {
+	if p == nil || len(p.AllowedEndpoints) == 0 {
+		return true
+	}
+	for _, a := range p.AllowedEndpoints {
+		a = strings.TrimRight(a, "/")
+		if url == a || strings.HasPrefix(url, a+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func matchWildcard(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, parts[len(parts)-1])
+}
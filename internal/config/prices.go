@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/markymn/nlcli/internal/usage"
+)
+
+// PricesPath is the price table that overrides usage.DefaultPrices.
+func PricesPath() string {
+	return filepath.Join(configDir, "prices.toml")
+}
+
+// LoadPrices returns the default price table overlaid with PricesPath.
+func LoadPrices() (map[string]usage.Price, error) {
+	prices := make(map[string]usage.Price, len(usage.DefaultPrices))
+	for model, p := range usage.DefaultPrices {
+		prices[model] = p
+	}
+
+	data, err := os.ReadFile(PricesPath())
+	if os.IsNotExist(err) {
+		return prices, nil
+	}
+	if err != nil {
+		return prices, err
+	}
+	custom, err := ParsePrices(string(data))
+	if err != nil {
+		return prices, fmt.Errorf("%s: %w", PricesPath(), err)
+	}
+	for model, p := range custom {
+		prices[model] = p
+	}
+	return prices, nil
+}
+
+// ParsePrices reads lines of the form "model" = [input, output], in USD per
+// million tokens.
+func ParsePrices(data string) (map[string]usage.Price, error) {
+	fields, err := parseFlatTOML(data)
+	if err != nil {
+		return nil, err
+	}
+	prices := make(map[string]usage.Price)
+	for _, f := range fields {
+		model, err := tomlValue(f.Key)
+		if err != nil {
+			return nil, f.errorf("%s", err)
+		}
+		if !f.Array || len(f.Values) != 2 {
+			return nil, f.errorf("%s: want [input, output] prices per million tokens", model)
+		}
+		var pair [2]float64
+		for i, v := range f.Values {
+			if pair[i], err = strconv.ParseFloat(v, 64); err != nil || pair[i] < 0 {
+				return nil, f.errorf("%s: invalid price %q", model, v)
+			}
+		}
+		prices[model] = usage.Price{Input: pair[0], Output: pair[1]}
+	}
+	return prices, nil
+}
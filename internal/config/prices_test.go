@@ -0,0 +1,27 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/markymn/nlcli/internal/usage"
+)
+
+func TestParsePrices(t *testing.T) {
+	prices, err := ParsePrices(`
+# USD per million tokens
+"gpt-4o" = [2.5, 10]
+'gemini-2.5-flash' = [0.30, 2.50]   # thinking tokens count as output
+`)
+	if err != nil {
+		t.Fatalf("ParsePrices() error = %v", err)
+	}
+	if prices["gpt-4o"] != (usage.Price{Input: 2.5, Output: 10}) || prices["gemini-2.5-flash"] != (usage.Price{Input: 0.3, Output: 2.5}) {
+		t.Errorf("ParsePrices() = %v", prices)
+	}
+
+	for _, bad := range []string{`"gpt-4o" = 2.5`, `"gpt-4o" = [2.5]`, `"gpt-4o" = [cheap, 1]`, `"gpt-4o" = [-1, 1]`} {
+		if _, err := ParsePrices(bad); err == nil {
+			t.Errorf("ParsePrices(%q) succeeded, want error", bad)
+		}
+	}
+}
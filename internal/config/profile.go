@@ -0,0 +1,169 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/markymn/nlcli/internal/shell"
+)
+
+const ProfileName = ".nlcli.toml"
+
+// Profile holds the settings a .nlcli.toml overrides for its directory tree.
+// Unset fields are nil (or 0 for levels) and keep the global value. Only a
+// Trusted profile may loosen the global settings; any other can only tighten
+// them, since it may have come with a cloned repository.
+type Profile struct {
+	Path            string
+	Safety          shell.SafetyLevel
+	Direct          *shell.SafetyLevel
+	Trash           *bool
+	ForbidElevation *bool
+	Trusted         bool
+	hash            string
+}
+
+// FindProfile looks for .nlcli.toml in dir and each parent up to the home
+// directory (or the filesystem root outside home). The nearest file wins.
+// Files another user could have written, because they own them or the file
+// is group- or world-writable, are skipped: a profile can lower the safety
+// level.
+func FindProfile(dir string) (*Profile, error) {
+	home, _ := os.UserHomeDir()
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		path := filepath.Join(dir, ProfileName)
+		if info, err := os.Stat(path); err == nil && !trustedFile(info) {
+			// not ours to trust; keep looking further up
+		} else if data, err := os.ReadFile(path); err == nil {
+			p, err := ParseProfile(string(data))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			p.Path = path
+			p.hash = profileHash(data)
+			p.Trusted = profileTrusted(path, p.hash)
+			return p, nil
+		}
+		parent := filepath.Dir(dir)
+		if dir == home || parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// trustedProfilesPath lists the profiles allowed to loosen settings, one
+// "sha256 path" line each, as direnv's allow list does. Editing a profile
+// changes its hash, so it has to be trusted again.
+func trustedProfilesPath() string {
+	return filepath.Join(configDir, "trusted_profiles")
+}
+
+func profileHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func profileTrusted(path, hash string) bool {
+	data, err := os.ReadFile(trustedProfilesPath())
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if h, p, ok := strings.Cut(line, " "); ok && h == hash && p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// TrustProfile lets p loosen the global settings for as long as its contents
+// stay the same.
+func TrustProfile(p *Profile) error {
+	var lines []string
+	if data, err := os.ReadFile(trustedProfilesPath()); err == nil {
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			if _, path, ok := strings.Cut(line, " "); ok && path != p.Path {
+				lines = append(lines, line)
+			}
+		}
+	}
+	lines = append(lines, p.hash+" "+p.Path)
+	if err := os.MkdirAll(configDir, 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(trustedProfilesPath(), []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		return err
+	}
+	p.Trusted = true
+	return nil
+}
+
+// ParseProfile reads the flat `key = value` subset of TOML a profile needs:
+//
+//	safety = "strict"      # instant, lax, cautious, strict or 1-4
+//	direct = "off"         # level from which typed commands are checked
+//	trash = true
+//	elevation = "forbid"   # or "allow"
+func ParseProfile(data string) (*Profile, error) {
+	fields, err := parseFlatTOML(data)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Profile{}
+	for _, f := range fields {
+		if f.Array {
+			return nil, f.errorf("%s does not take an array", f.Key)
+		}
+		value := f.Value()
+		switch f.Key {
+		case "safety":
+			level, ok := shell.ParseSafetyLevel(value)
+			if !ok {
+				return nil, f.errorf("unknown safety level %q", value)
+			}
+			p.Safety = level
+		case "direct":
+			level, ok := parseDirectLevel(value)
+			if !ok {
+				return nil, f.errorf("direct must be off, lax, cautious or strict")
+			}
+			p.Direct = &level
+		case "trash":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, f.errorf("trash must be true or false")
+			}
+			p.Trash = &b
+		case "elevation":
+			if value != "allow" && value != "forbid" {
+				return nil, f.errorf("elevation must be allow or forbid")
+			}
+			forbid := value == "forbid"
+			p.ForbidElevation = &forbid
+		default:
+			return nil, f.errorf("unknown key %q", f.Key)
+		}
+	}
+	return p, nil
+}
+
+// parseDirectLevel accepts "off" (0) or a safety level above Instant.
+func parseDirectLevel(value string) (shell.SafetyLevel, bool) {
+	if strings.EqualFold(value, "off") {
+		return 0, true
+	}
+	level, ok := shell.ParseSafetyLevel(value)
+	return level, ok && level != shell.SafetyInstant
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/markymn/nlcli/internal/shell"
+)
+
+func TestParseProfile(t *testing.T) {
+	p, err := ParseProfile(`
+# deploy repo: confirm everything
+safety = "strict"
+direct = 'lax'   # typed commands too
+trash = true
+elevation = "forbid"
+`)
+	if err != nil {
+		t.Fatalf("ParseProfile() error = %v", err)
+	}
+	if p.Safety != shell.SafetyStrict {
+		t.Errorf("Safety = %v, want Strict", p.Safety)
+	}
+	if p.Direct == nil || *p.Direct != shell.SafetyLax {
+		t.Errorf("Direct = %v, want Lax", p.Direct)
+	}
+	if p.Trash == nil || !*p.Trash {
+		t.Errorf("Trash = %v, want true", p.Trash)
+	}
+	if p.ForbidElevation == nil || !*p.ForbidElevation {
+		t.Errorf("ForbidElevation = %v, want true", p.ForbidElevation)
+	}
+
+	for _, bad := range []string{`safety = "paranoid"`, `colour = "red"`, `safety "strict"`, `direct = "instant"`, `trash = "maybe"`} {
+		if _, err := ParseProfile(bad); err == nil {
+			t.Errorf("ParseProfile(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestFindProfile(t *testing.T) {
+	root := t.TempDir()
+	deep := filepath.Join(root, "deploy", "env", "prod")
+	os.MkdirAll(deep, 0755)
+	os.WriteFile(filepath.Join(root, "deploy", ProfileName), []byte("safety = 4\n"), 0644)
+
+	p, err := FindProfile(deep)
+	if err != nil || p == nil {
+		t.Fatalf("FindProfile() = %v, %v", p, err)
+	}
+	if p.Safety != shell.SafetyStrict || p.Path != filepath.Join(root, "deploy", ProfileName) {
+		t.Errorf("FindProfile() = %+v", p)
+	}
+
+	if p, err := FindProfile(root); err != nil || p != nil {
+		t.Errorf("FindProfile(outside) = %v, %v, want nil", p, err)
+	}
+}
+
+func TestFindProfileSkipsUntrusted(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no file ownership or permission bits to check")
+	}
+	root := t.TempDir()
+	dir := filepath.Join(root, "shared")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(root, ProfileName), []byte("safety = 4\n"), 0644)
+	path := filepath.Join(dir, ProfileName)
+	os.WriteFile(path, []byte("safety = \"instant\"\n"), 0644)
+
+	tests := []struct {
+		name  string
+		setup func() error
+	}{
+		{"world-writable", func() error { return os.Chmod(path, 0666) }},
+		{"group-writable", func() error { return os.Chmod(path, 0664) }},
+		{"owned by another user", func() error {
+			if os.Getuid() != 0 {
+				t.Skip("changing a file's owner needs root")
+			}
+			os.Chmod(path, 0644)
+			return os.Chown(path, 12345, 12345)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.setup(); err != nil {
+				t.Skip(err)
+			}
+			p, err := FindProfile(dir)
+			if err != nil || p == nil || p.Safety != shell.SafetyStrict {
+				t.Errorf("FindProfile() = %+v, %v; want the trusted profile further up", p, err)
+			}
+		})
+	}
+}
+
+func TestTrustProfile(t *testing.T) {
+	oldDir := configDir
+	configDir = t.TempDir()
+	defer func() { configDir = oldDir }()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, ProfileName)
+	os.WriteFile(path, []byte("safety = \"instant\"\n"), 0644)
+
+	p, err := FindProfile(dir)
+	if err != nil || p == nil {
+		t.Fatalf("FindProfile() = %v, %v", p, err)
+	}
+	if p.Trusted {
+		t.Fatal("new profile is trusted without .trust")
+	}
+	if err := TrustProfile(p); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := FindProfile(dir); p == nil || !p.Trusted {
+		t.Error("trusted profile not recognised")
+	}
+
+	// any edit needs trusting again
+	os.WriteFile(path, []byte("safety = \"instant\"\ndirect = \"off\"\n"), 0644)
+	if p, _ := FindProfile(dir); p == nil || p.Trusted {
+		t.Error("edited profile still trusted")
+	}
+}
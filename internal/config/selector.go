@@ -21,17 +21,25 @@ func SelectModel(models []string, providerName string) (string, error) {
 }
 
 func SelectGeneric(options []string, title string) (string, error) {
+	return SelectWithDefault(options, title, 0)
+}
+
+// SelectWithDefault is SelectGeneric with the cursor starting on options[def].
+func SelectWithDefault(options []string, title string, def int) (string, error) {
 	if len(options) == 0 {
 		return "", fmt.Errorf("no options available")
 	}
+	if def < 0 || def >= len(options) {
+		def = 0
+	}
 
 	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
 	if err != nil {
-		return options[0], nil
+		return options[def], nil
 	}
 	defer term.Restore(int(os.Stdin.Fd()), oldState)
 
-	selected := 0
+	selected := def
 	buf := make([]byte, 3)
 
 	fmt.Printf("\r\n%s (use ↑↓ arrows, Enter to select):\r\n", title)
@@ -50,7 +58,7 @@ func SelectGeneric(options []string, title string) (string, error) {
 				return options[selected], nil
 			case 'q', 3:
 				clearOptions(len(options) + 1)
-				return options[0], nil
+				return options[def], nil
 			}
 		} else if n == 3 && buf[0] == keyESC && buf[1] == '[' {
 			switch buf[2] {
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tomlField is one `key = value` line. Arrays of strings are flattened into
+// Values; scalars have exactly one value.
+type tomlField struct {
+	Line   int
+	Key    string
+	Values []string
+	Array  bool
+}
+
+func (f tomlField) Value() string {
+	return f.Values[0]
+}
+
+func (f tomlField) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: "+format, append([]any{f.Line}, args...)...)
+}
+
+// parseFlatTOML reads the small subset of TOML nlcli's files use: top-level
+// `key = value` pairs with strings, bare words and one-line string arrays.
+func parseFlatTOML(data string) ([]tomlField, error) {
+	var fields []tomlField
+	for n, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(stripComment(line))
+		if line == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", n+1)
+		}
+		f := tomlField{Line: n + 1, Key: strings.TrimSpace(key)}
+		value = strings.TrimSpace(value)
+
+		if strings.HasPrefix(value, "[") {
+			if !strings.HasSuffix(value, "]") {
+				return nil, f.errorf("unterminated array")
+			}
+			f.Array = true
+			for _, item := range splitArray(value[1 : len(value)-1]) {
+				v, err := tomlValue(item)
+				if err != nil {
+					return nil, f.errorf("%s", err)
+				}
+				f.Values = append(f.Values, v)
+			}
+		} else {
+			v, err := tomlValue(value)
+			if err != nil {
+				return nil, f.errorf("%s", err)
+			}
+			f.Values = []string{v}
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// splitArray splits array items on commas outside quotes.
+func splitArray(s string) []string {
+	var items []string
+	var quote rune
+	start := 0
+	for i, c := range s {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			items = append(items, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		items = append(items, last)
+	}
+	return items
+}
+
+// stripComment drops a # comment unless it sits inside a quoted string.
+func stripComment(line string) string {
+	var quote rune
+	for i, c := range line {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func tomlValue(s string) (string, error) {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') {
+		if s[len(s)-1] != s[0] {
+			return "", fmt.Errorf("unterminated string")
+		}
+		return s[1 : len(s)-1], nil
+	}
+	if s == "" || s[0] == '"' || s[0] == '\'' {
+		return "", fmt.Errorf("missing value")
+	}
+	return s, nil
+}
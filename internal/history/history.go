@@ -61,6 +61,17 @@ func (h *History) Format() string {
 	return sb.String()
 }
 
+// Map returns a copy of the history with f applied to every command and
+// output.
+func (h *History) Map(f func(string) string) *History {
+	c := *h
+	c.entries = make([]Entry, len(h.entries))
+	for i, e := range h.entries {
+		c.entries[i] = Entry{Command: f(e.Command), Output: f(e.Output)}
+	}
+	return &c
+}
+
 func (h *History) Last() *Entry {
 	if len(h.entries) == 0 {
 		return nil
@@ -2,6 +2,7 @@ package provider
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -32,51 +33,95 @@ func (c *Anthropic) Model() string {
 	return c.model
 }
 
-func (c *Anthropic) GetCommand(userInput, cwd string, shellType shell.ShellType, hist *history.History) (string, error) {
-	prompt := BuildSystemPrompt(userInput, cwd, shellType, hist)
+func (c *Anthropic) GetCommand(ctx context.Context, userInput, cwd string, shellType shell.ShellType, hist *history.History) (Reply, error) {
+	return c.Complete(ctx, BuildSystemPrompt(userInput, cwd, shellType, hist))
+}
 
-	reqBody, _ := json.Marshal(map[string]interface{}{
+func (c *Anthropic) Complete(ctx context.Context, prompt string) (Reply, error) {
+	params := requestParams(ctx, "anthropic", c.model)
+	body := map[string]interface{}{
 		"model": c.model,
 		"messages": []map[string]string{
 			{"role": "user", "content": prompt},
 		},
-		"max_tokens": 300,
-	})
+		"max_tokens": params.MaxTokens,
+	}
+	if budget := thinkingTokens(params, ModelCapabilities("anthropic", c.model)); budget > 0 {
+		// the thinking budget is part of max_tokens, and thinking only
+		// works with the default temperature
+		body["thinking"] = map[string]interface{}{"type": "enabled", "budget_tokens": budget}
+		body["max_tokens"] = params.MaxTokens + budget
+	} else {
+		if params.Temperature != nil {
+			body["temperature"] = *params.Temperature
+		}
+		if params.TopP != nil {
+			body["top_p"] = *params.TopP
+		}
+	}
+	if len(params.Stop) > 0 {
+		body["stop_sequences"] = params.Stop
+	}
+	reqBody, _ := json.Marshal(body)
 
-	req, _ := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(reqBody))
+	req, _ := http.NewRequestWithContext(ctx, "POST", Endpoints["anthropic"]+"/v1/messages", bytes.NewBuffer(reqBody))
 	req.Header.Set("x-api-key", c.apiKey)
 	req.Header.Set("anthropic-version", "2023-06-01")
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return "", err
+		return Reply{}, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("api error: %s", resp.Status)
+		return Reply{}, fmt.Errorf("api error: %s", resp.Status)
 	}
 
 	var result struct {
+		StopReason string `json:"stop_reason"`
+		Usage      struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
 		Content []struct {
-			Text string `json:"text"`
+			Type     string `json:"type"`
+			Text     string `json:"text"`
+			Thinking string `json:"thinking"`
 		} `json:"content"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+		return Reply{}, err
 	}
 
-	if len(result.Content) == 0 {
-		return "", fmt.Errorf("no response")
+	// with extended thinking the answer follows a thinking block
+	var text, thinking strings.Builder
+	for _, block := range result.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "thinking":
+			thinking.WriteString(block.Thinking)
+		}
+	}
+	finish := classifyFinish(result.StopReason)
+	if text.Len() == 0 && finish == FinishComplete {
+		return Reply{}, fmt.Errorf("no response")
 	}
 
-	return strings.TrimSpace(result.Content[0].Text), nil
+	return Reply{
+		Text:         strings.TrimSpace(text.String()),
+		Usage:        Usage{InputTokens: result.Usage.InputTokens, OutputTokens: result.Usage.OutputTokens},
+		Finish:       finish,
+		FinishReason: result.StopReason,
+		Reasoning:    strings.TrimSpace(thinking.String()),
+	}, nil
 }
 
 func FetchAnthropicModels(apiKey string) ([]string, error) {
-	req, _ := http.NewRequest("GET", "https://api.anthropic.com/v1/models", nil)
+	req, _ := http.NewRequest("GET", Endpoints["anthropic"]+"/v1/models", nil)
 	req.Header.Set("x-api-key", apiKey)
 	req.Header.Set("anthropic-version", "2023-06-01")
 
@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/markymn/nlcli/internal/usage"
+)
+
+// SetBudget limits requests once the ledger shows b exceeded. It needs a
+// ledger to take effect.
+func (m *MultiClient) SetBudget(b *usage.Budget) {
+	m.budget = b
+}
+
+// BudgetNote explains how the budget changed the last request, or is "".
+func (m *MultiClient) BudgetNote() string {
+	return m.budgetNote
+}
+
+// admit returns the providers a request may go to under the budget: the
+// chain as configured, only a cheaper model, or none.
+func (m *MultiClient) admit() ([]Provider, error) {
+	links := append([]Provider{m.primary}, m.fallbacks...)
+	m.budgetNote = ""
+	if m.budget == nil || m.ledger == nil {
+		return links, nil
+	}
+
+	reason, rateLimited, err := m.ledger.Check(*m.budget)
+	if err != nil {
+		m.budgetNote = "could not check the budget: " + err.Error()
+		return links, nil
+	}
+	switch {
+	case reason == "":
+		return links, nil
+	case m.budget.Action == usage.ActionWarn:
+		m.budgetNote = "over budget: " + reason
+		return links, nil
+	case rateLimited || m.budget.Action == usage.ActionRefuse:
+		return nil, fmt.Errorf("over budget: %s (%s)", reason, m.budget.Path)
+	}
+
+	model := m.cheapModel()
+	if model == "" {
+		return nil, fmt.Errorf("over budget: %s, and no cheaper %s model is known (%s)", reason, m.primary.Name(), m.budget.Path)
+	}
+	m.budgetNote = fmt.Sprintf("over budget: %s; using %s", reason, model)
+	return []Provider{createProvider(m.primaryName, m.apiKey, model)}, nil
+}
+
+// cheapModel returns the budget's cheap model, or else the cheapest priced
+// model of the primary's provider, when it is cheaper than the primary.
+func (m *MultiClient) cheapModel() string {
+	if m.budget.CheapModel != "" {
+		return m.budget.CheapModel
+	}
+
+	name := m.primary.Name()
+	cost := func(model string) (float64, bool) {
+		p, ok := m.ledger.Price(name, model)
+		return p.Input + p.Output, ok
+	}
+
+	current, ok := cost(m.primary.Model())
+	if !ok {
+		return ""
+	}
+	best, bestCost := "", current
+	for _, model := range GetModels(m.primaryName) {
+		if c, ok := cost(model); ok && c < bestCost {
+			best, bestCost = model, c
+		}
+	}
+	return best
+}
@@ -0,0 +1,147 @@
+package provider
+
+import (
+	"strings"
+
+	"github.com/markymn/nlcli/internal/config"
+)
+
+// Capabilities describe how a model family is asked for an answer.
+type Capabilities struct {
+	// Reasoning models think before answering unless told not to, and the
+	// thinking counts against the token limit.
+	Reasoning bool
+	// AlwaysThinks means thinking cannot be turned off.
+	AlwaysThinks bool
+	// Effort models take reasoning_effort.
+	Effort bool
+	// Budget models take a thinking budget in tokens, no smaller than
+	// MinBudget.
+	Budget    bool
+	MinBudget int
+	// FixedSampling models reject temperature, top_p and stop.
+	FixedSampling bool
+	// ParsedReasoning models on Groq write their thinking into the answer
+	// unless reasoning_format is "parsed".
+	ParsedReasoning bool
+}
+
+type family struct {
+	provider string
+	prefix   string
+	caps     Capabilities
+}
+
+// families is matched by the longest model prefix, so exceptions follow the
+// family they belong to.
+var families = []family{
+	{"openai", "o1", Capabilities{Reasoning: true, AlwaysThinks: true, Effort: true, FixedSampling: true}},
+	{"openai", "o1-mini", Capabilities{Reasoning: true, AlwaysThinks: true, FixedSampling: true}},
+	{"openai", "o1-preview", Capabilities{Reasoning: true, AlwaysThinks: true, FixedSampling: true}},
+	{"openai", "o3", Capabilities{Reasoning: true, AlwaysThinks: true, Effort: true, FixedSampling: true}},
+	{"openai", "o4", Capabilities{Reasoning: true, AlwaysThinks: true, Effort: true, FixedSampling: true}},
+	{"openai", "gpt-5", Capabilities{Reasoning: true, AlwaysThinks: true, Effort: true, FixedSampling: true}},
+	{"openai", "gpt-5-chat", Capabilities{}},
+
+	{"anthropic", "claude-3-7-sonnet", Capabilities{Budget: true, MinBudget: 1024}},
+	{"anthropic", "claude-sonnet-4", Capabilities{Budget: true, MinBudget: 1024}},
+	{"anthropic", "claude-opus-4", Capabilities{Budget: true, MinBudget: 1024}},
+	{"anthropic", "claude-haiku-4", Capabilities{Budget: true, MinBudget: 1024}},
+
+	{"google", "gemini-2.5", Capabilities{Reasoning: true, Budget: true}},
+	{"google", "gemini-2.5-pro", Capabilities{Reasoning: true, AlwaysThinks: true, Budget: true, MinBudget: 128}},
+	{"google", "gemini-2.5-flash-lite", Capabilities{Budget: true, MinBudget: 512}},
+	{"google", "gemini-3", Capabilities{Reasoning: true, AlwaysThinks: true}},
+
+	{"groq", "openai/gpt-oss", Capabilities{Reasoning: true, AlwaysThinks: true, Effort: true}},
+	{"groq", "deepseek-r1", Capabilities{Reasoning: true, AlwaysThinks: true, ParsedReasoning: true}},
+	{"groq", "qwen/qwen3", Capabilities{Reasoning: true, ParsedReasoning: true}},
+
+	{"ollama", "deepseek-r1", Capabilities{Reasoning: true}},
+	{"ollama", "qwen3", Capabilities{Reasoning: true}},
+	{"ollama", "gpt-oss", Capabilities{Reasoning: true}},
+	{"ollama", "magistral", Capabilities{Reasoning: true}},
+}
+
+// ModelCapabilities returns what the named provider's model supports. Models
+// outside every known family are plain chat models.
+func ModelCapabilities(name, model string) Capabilities {
+	model = strings.ToLower(model)
+	var best family
+	for _, f := range families {
+		if f.provider == name && strings.HasPrefix(model, f.prefix) && len(f.prefix) > len(best.prefix) {
+			best = f
+		}
+	}
+	return best.caps
+}
+
+// defaultThinkingTokens is added to the token limit of models that think
+// without a configured budget, so the thinking does not crowd out the
+// command.
+const defaultThinkingTokens = 4096
+
+// thinkingTokens returns how many tokens a model with caps may think for:
+// the configured budget, raised to what the API accepts, or
+// defaultThinkingTokens for reasoning models without one. 0 means the model
+// does not think.
+func thinkingTokens(p config.Params, caps Capabilities) int {
+	if !caps.Reasoning && !caps.Budget {
+		return 0
+	}
+	if p.ThinkingBudget == nil {
+		if caps.Reasoning {
+			return defaultThinkingTokens
+		}
+		return 0
+	}
+	n := *p.ThinkingBudget
+	if n == 0 && !caps.AlwaysThinks {
+		return 0
+	}
+	if n == 0 && caps.MinBudget == 0 {
+		// thinking cannot be turned off and the API takes no budget
+		return defaultThinkingTokens
+	}
+	return max(n, caps.MinBudget)
+}
+
+// reasoningEffort is the configured effort, or "low" since a shell command
+// rarely needs more.
+func reasoningEffort(p config.Params) string {
+	if p.ReasoningEffort == "" {
+		return "low"
+	}
+	return p.ReasoningEffort
+}
+
+// Thinks reports whether model of the named provider reasons before
+// answering with the current parameters.
+func Thinks(name, model string) bool {
+	return thinkingTokens(Params(name, model), ModelCapabilities(name, model)) > 0
+}
+
+// Thinks reports whether any provider in the chain reasons before answering.
+func (m *MultiClient) Thinks() bool {
+	for _, p := range append([]Provider{m.primary}, m.fallbacks...) {
+		if Thinks(strings.ToLower(p.Name()), p.Model()) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitThinking separates a <think>...</think> block some open models put
+// before their answer.
+func splitThinking(text string) (answer, thinking string) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(text), "<think>")
+	if !ok {
+		return text, ""
+	}
+	thinking, answer, ok = strings.Cut(rest, "</think>")
+	if !ok {
+		// the answer never started
+		return "", strings.TrimSpace(rest)
+	}
+	return strings.TrimSpace(answer), strings.TrimSpace(thinking)
+}
@@ -0,0 +1,46 @@
+package provider
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Link is one entry of a fallback chain.
+type Link struct {
+	Provider string
+	Model    string
+	APIKey   string
+}
+
+// ParseLink splits a chain entry written as provider or provider/model.
+func ParseLink(entry string) (name, model string, err error) {
+	name, model, _ = strings.Cut(strings.TrimSpace(entry), "/")
+	name = strings.ToLower(name)
+	if !slices.Contains(Names, name) {
+		return "", "", fmt.Errorf("unknown provider %q in %q", name, entry)
+	}
+	return name, model, nil
+}
+
+// SetChain replaces the fallbacks with links, tried in order when the
+// primary fails.
+func (m *MultiClient) SetChain(links []Link) {
+	m.fallbacks = nil
+	for _, l := range links {
+		if p := createProvider(l.Provider, l.APIKey, l.Model); p != nil {
+			m.fallbacks = append(m.fallbacks, p)
+		}
+	}
+}
+
+// Answered returns the provider that produced the last command and its
+// position in the chain, 0 being the primary.
+func (m *MultiClient) Answered() (Provider, int) {
+	return m.answered, m.answeredLink
+}
+
+// ChainLength counts the primary and its fallbacks.
+func (m *MultiClient) ChainLength() int {
+	return 1 + len(m.fallbacks)
+}
@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/markymn/nlcli/internal/history"
+	"github.com/markymn/nlcli/internal/redact"
+	"github.com/markymn/nlcli/internal/shell"
+	"github.com/markymn/nlcli/internal/usage"
+)
+
+// Comparison is one provider's answer to a request sent to several.
+type Comparison struct {
+	Provider string
+	Model    string
+	Command  string
+	Latency  time.Duration
+	Usage    Usage
+	Err      error
+}
+
+// Compare sends the request to the primary, the fallbacks and extra all at
+// once and returns every answer, in that order. Secrets are redacted as in
+// GetCommand. Over budget it only goes ahead when the budget merely warns.
+func (m *MultiClient) Compare(extra []Link, userInput, cwd string, shellType shell.ShellType, hist *history.History) ([]Comparison, error) {
+	if _, err := m.admit(); err != nil {
+		return nil, err
+	}
+	if m.budgetNote != "" && m.budget.Action != usage.ActionWarn {
+		return nil, fmt.Errorf("%s; .compare is off until then", m.budgetNote)
+	}
+
+	red := redact.New()
+	userInput, cwd, hist = red.Redact(userInput), red.Redact(cwd), hist.Map(red.Redact)
+	m.redacted, m.redactor = red.Count(), red
+
+	links := append([]Provider{m.primary}, m.fallbacks...)
+	for _, l := range extra {
+		if p := createProvider(l.Provider, l.APIKey, l.Model); p != nil {
+			links = append(links, p)
+		}
+	}
+
+	var providers []Provider
+	seen := make(map[string]bool)
+	for _, p := range links {
+		if key := p.Name() + "/" + p.Model(); !seen[key] {
+			seen[key] = true
+			providers = append(providers, p)
+		}
+	}
+
+	ask := m.checked(func(ctx context.Context, p Provider) (Reply, error) {
+		return p.GetCommand(ctx, userInput, cwd, shellType, hist)
+	})
+	results := make([]Comparison, len(providers))
+	done := make(chan struct{})
+	for i, p := range providers {
+		go func() {
+			start := time.Now()
+			reply, err := ask(context.Background(), p)
+			results[i] = Comparison{
+				Provider: p.Name(),
+				Model:    p.Model(),
+				Command:  reply.Text,
+				Latency:  time.Since(start),
+				Usage:    reply.Usage,
+				Err:      err,
+			}
+			done <- struct{}{}
+		}()
+	}
+	for range providers {
+		<-done
+	}
+	for i, c := range results {
+		if c.Err == nil || c.Usage.Total() > 0 {
+			m.record(providers[i], c.Usage)
+		}
+	}
+	return results, nil
+}
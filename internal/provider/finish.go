@@ -0,0 +1,80 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/markymn/nlcli/internal/config"
+)
+
+// Finish says why a model stopped generating.
+type Finish int
+
+const (
+	// FinishComplete means the model ended its answer or hit a stop sequence.
+	FinishComplete Finish = iota
+	// FinishLength means the answer was cut off by the token limit.
+	FinishLength
+	// FinishFiltered means a safety filter stopped or withheld the answer.
+	FinishFiltered
+)
+
+// classifyFinish maps the stop reasons the APIs report (finish_reason,
+// stop_reason, finishReason, done_reason) to a Finish. Unknown reasons count
+// as complete.
+func classifyFinish(reason string) Finish {
+	switch strings.ToLower(reason) {
+	case "length", "max_tokens":
+		return FinishLength
+	case "content_filter", "refusal", "safety", "recitation", "blocklist",
+		"prohibited_content", "spii", "image_safety":
+		return FinishFiltered
+	}
+	return FinishComplete
+}
+
+// retryScale multiplies the token limit when a cut-off reply is retried.
+const retryScale = 4
+
+type tokenScaleKey struct{}
+
+// requestParams is Params with the token limit raised for a retry.
+func requestParams(ctx context.Context, name, model string) config.Params {
+	p := Params(name, model)
+	if scale, ok := ctx.Value(tokenScaleKey{}).(int); ok {
+		p.MaxTokens *= scale
+	}
+	return p
+}
+
+// checked wraps ask so a reply cut off by the token limit is asked for again
+// with a larger limit, and a reply that is still cut off or was stopped by a
+// safety filter becomes an error. A partial command is never returned.
+func (m *MultiClient) checked(ask askFunc) askFunc {
+	return func(ctx context.Context, p Provider) (Reply, error) {
+		reply, err := ask(ctx, p)
+		if err == nil && reply.Finish == FinishLength {
+			m.record(p, reply.Usage)
+			reply, err = ask(context.WithValue(ctx, tokenScaleKey{}, retryScale), p)
+			reply.Retried = true
+		}
+		if err != nil {
+			return reply, err
+		}
+		switch reply.Finish {
+		case FinishLength:
+			return reply, fmt.Errorf("the reply was cut off by the token limit (%s), even with %d times the limit; raise max_tokens in %s",
+				reply.FinishReason, retryScale, config.GenerationPath())
+		case FinishFiltered:
+			return reply, fmt.Errorf("the provider's safety filter stopped the reply (%s)", reply.FinishReason)
+		}
+		return reply, nil
+	}
+}
+
+// Retried reports whether the last command had to be asked for again because
+// the first reply was cut off.
+func (m *MultiClient) Retried() bool {
+	return m.retried
+}
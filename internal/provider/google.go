@@ -2,6 +2,7 @@ package provider
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -32,9 +33,37 @@ func (g *Google) Model() string {
 	return g.model
 }
 
-func (g *Google) GetCommand(userInput, cwd string, shellType shell.ShellType, hist *history.History) (string, error) {
-	prompt := BuildSystemPrompt(userInput, cwd, shellType, hist)
+func (g *Google) GetCommand(ctx context.Context, userInput, cwd string, shellType shell.ShellType, hist *history.History) (Reply, error) {
+	return g.Complete(ctx, BuildSystemPrompt(userInput, cwd, shellType, hist))
+}
 
+func (g *Google) Complete(ctx context.Context, prompt string) (Reply, error) {
+	params := requestParams(ctx, "google", g.model)
+	genConfig := map[string]interface{}{
+		"maxOutputTokens": params.MaxTokens,
+	}
+	if params.Temperature != nil {
+		genConfig["temperature"] = *params.Temperature
+	}
+	if params.TopP != nil {
+		genConfig["topP"] = *params.TopP
+	}
+	if len(params.Stop) > 0 {
+		genConfig["stopSequences"] = params.Stop
+	}
+	if params.Seed != nil {
+		genConfig["seed"] = *params.Seed
+	}
+	if caps := ModelCapabilities("google", g.model); caps.Reasoning || caps.Budget {
+		// thinking tokens count against maxOutputTokens
+		budget := thinkingTokens(params, caps)
+		thinkingConfig := map[string]interface{}{"includeThoughts": budget > 0}
+		if caps.Budget && params.ThinkingBudget != nil {
+			thinkingConfig["thinkingBudget"] = budget
+		}
+		genConfig["thinkingConfig"] = thinkingConfig
+		genConfig["maxOutputTokens"] = params.MaxTokens + budget
+	}
 	reqBody, _ := json.Marshal(map[string]interface{}{
 		"contents": []map[string]interface{}{
 			{
@@ -43,48 +72,82 @@ func (g *Google) GetCommand(userInput, cwd string, shellType shell.ShellType, hi
 				},
 			},
 		},
-		"generationConfig": map[string]interface{}{
-			"maxOutputTokens": 300,
-		},
+		"generationConfig": genConfig,
 	})
 
-	url := "https://generativelanguage.googleapis.com/v1beta/models/" + g.model + ":generateContent?key=" + g.apiKey
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
+	url := Endpoints["google"] + "/v1beta/models/" + g.model + ":generateContent?key=" + g.apiKey
+	req, _ := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return "", err
+		return Reply{}, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("api error: %s", resp.Status)
+		return Reply{}, fmt.Errorf("api error: %s", resp.Status)
 	}
 
 	var result struct {
+		UsageMetadata struct {
+			PromptTokenCount     int `json:"promptTokenCount"`
+			CandidatesTokenCount int `json:"candidatesTokenCount"`
+			ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+		} `json:"usageMetadata"`
+		PromptFeedback struct {
+			BlockReason string `json:"blockReason"`
+		} `json:"promptFeedback"`
 		Candidates []struct {
 			Content struct {
 				Parts []struct {
-					Text string `json:"text"`
+					Text    string `json:"text"`
+					Thought bool   `json:"thought"`
 				} `json:"parts"`
 			} `json:"content"`
+			FinishReason string `json:"finishReason"`
 		} `json:"candidates"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+		return Reply{}, err
 	}
 
-	if len(result.Candidates) == 0 || len(result.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("no response")
+	// thinking tokens are billed as output
+	usage := result.UsageMetadata
+	reply := Reply{
+		Usage: Usage{InputTokens: usage.PromptTokenCount, OutputTokens: usage.CandidatesTokenCount + usage.ThoughtsTokenCount},
+	}
+	if len(result.Candidates) == 0 {
+		// a blocked prompt gets no candidates at all
+		if reason := result.PromptFeedback.BlockReason; reason != "" {
+			reply.Finish, reply.FinishReason = FinishFiltered, reason
+			return reply, nil
+		}
+		return Reply{}, fmt.Errorf("no response")
 	}
 
-	return strings.TrimSpace(result.Candidates[0].Content.Parts[0].Text), nil
+	candidate := result.Candidates[0]
+	reply.Finish, reply.FinishReason = classifyFinish(candidate.FinishReason), candidate.FinishReason
+	// thought summaries come as parts of their own before the answer
+	var text, thoughts strings.Builder
+	for _, part := range candidate.Content.Parts {
+		if part.Thought {
+			thoughts.WriteString(part.Text)
+		} else {
+			text.WriteString(part.Text)
+		}
+	}
+	if text.Len() == 0 && reply.Finish == FinishComplete {
+		return Reply{}, fmt.Errorf("no response")
+	}
+	reply.Text = strings.TrimSpace(text.String())
+	reply.Reasoning = strings.TrimSpace(thoughts.String())
+	return reply, nil
 }
 
 func FetchGoogleModels(apiKey string) ([]string, error) {
-	req, _ := http.NewRequest("GET", "https://generativelanguage.googleapis.com/v1beta/models?key="+apiKey, nil)
+	req, _ := http.NewRequest("GET", Endpoints["google"]+"/v1beta/models?key="+apiKey, nil)
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -2,6 +2,7 @@ package provider
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -39,9 +40,16 @@ func (g *Groq) Model() string {
 }
 
 type groqRequest struct {
-	Model     string    `json:"model"`
-	Messages  []groqMsg `json:"messages"`
-	MaxTokens int       `json:"max_tokens"`
+	Model       string    `json:"model"`
+	Messages    []groqMsg `json:"messages"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	TopP        *float64  `json:"top_p,omitempty"`
+	Stop        []string  `json:"stop,omitempty"`
+	Seed        *int64    `json:"seed,omitempty"`
+	// reasoning models only
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+	ReasoningFormat string `json:"reasoning_format,omitempty"`
 }
 
 type groqMsg struct {
@@ -52,33 +60,53 @@ type groqMsg struct {
 type groqResponse struct {
 	Choices []struct {
 		Message struct {
-			Content string `json:"content"`
+			Content   string `json:"content"`
+			Reasoning string `json:"reasoning"`
 		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
 	Error *struct {
 		Message string `json:"message"`
 	} `json:"error"`
 }
 
-func (g *Groq) GetCommand(userInput, cwd string, shellType shell.ShellType, hist *history.History) (string, error) {
-	prompt := BuildSystemPrompt(userInput, cwd, shellType, hist)
+func (g *Groq) GetCommand(ctx context.Context, userInput, cwd string, shellType shell.ShellType, hist *history.History) (Reply, error) {
+	return g.Complete(ctx, BuildSystemPrompt(userInput, cwd, shellType, hist))
+}
 
+func (g *Groq) Complete(ctx context.Context, prompt string) (Reply, error) {
+	params := requestParams(ctx, "groq", g.model)
+	caps := ModelCapabilities("groq", g.model)
 	reqBody := groqRequest{
-		Model:     g.model,
-		MaxTokens: 300,
+		Model:       g.model,
+		MaxTokens:   params.MaxTokens + thinkingTokens(params, caps),
+		Temperature: params.Temperature,
+		TopP:        params.TopP,
+		Stop:        params.Stop,
+		Seed:        params.Seed,
 		Messages: []groqMsg{
 			{Role: "user", Content: prompt},
 		},
 	}
+	if caps.Effort {
+		reqBody.ReasoningEffort = reasoningEffort(params)
+	}
+	if caps.ParsedReasoning {
+		reqBody.ReasoningFormat = "parsed"
+	}
 
 	body, _ := json.Marshal(reqBody)
-	req, _ := http.NewRequest("POST", "https://api.groq.com/openai/v1/chat/completions", bytes.NewReader(body))
+	req, _ := http.NewRequestWithContext(ctx, "POST", Endpoints["groq"]+"/openai/v1/chat/completions", bytes.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+g.apiKey)
 
 	resp, err := g.client.Do(req)
 	if err != nil {
-		return "", err
+		return Reply{}, err
 	}
 	defer resp.Body.Close()
 
@@ -86,22 +114,33 @@ func (g *Groq) GetCommand(userInput, cwd string, shellType shell.ShellType, hist
 
 	var result groqResponse
 	if err := json.Unmarshal(respBody, &result); err != nil {
-		return "", err
+		return Reply{}, err
 	}
 
 	if result.Error != nil {
-		return "", fmt.Errorf("%s", result.Error.Message)
+		return Reply{}, fmt.Errorf("%s", result.Error.Message)
 	}
 
 	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("no response")
+		return Reply{}, fmt.Errorf("no response")
 	}
 
-	return strings.TrimSpace(result.Choices[0].Message.Content), nil
+	choice := result.Choices[0]
+	text, thinking := splitThinking(choice.Message.Content)
+	if choice.Message.Reasoning != "" {
+		thinking = choice.Message.Reasoning
+	}
+	return Reply{
+		Text:         strings.TrimSpace(text),
+		Reasoning:    strings.TrimSpace(thinking),
+		Usage:        Usage{InputTokens: result.Usage.PromptTokens, OutputTokens: result.Usage.CompletionTokens},
+		Finish:       classifyFinish(choice.FinishReason),
+		FinishReason: choice.FinishReason,
+	}, nil
 }
 
 func FetchGroqModels(apiKey string) ([]string, error) {
-	req, _ := http.NewRequest("GET", "https://api.groq.com/openai/v1/models", nil)
+	req, _ := http.NewRequest("GET", Endpoints["groq"]+"/openai/v1/models", nil)
 	req.Header.Set("Authorization", "Bearer "+apiKey)
 
 	resp, err := http.DefaultClient.Do(req)
@@ -0,0 +1,140 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/markymn/nlcli/internal/history"
+	"github.com/markymn/nlcli/internal/shell"
+)
+
+// Ollama talks to a local Ollama server, which needs no API key.
+type Ollama struct {
+	model string
+}
+
+func NewOllama(model string) *Ollama {
+	if model == "" {
+		model = "llama3.2"
+	}
+	return &Ollama{model: model}
+}
+
+func (o *Ollama) Name() string {
+	return "Ollama"
+}
+
+func (o *Ollama) Model() string {
+	return o.model
+}
+
+func (o *Ollama) GetCommand(ctx context.Context, userInput, cwd string, shellType shell.ShellType, hist *history.History) (Reply, error) {
+	return o.Complete(ctx, BuildSystemPrompt(userInput, cwd, shellType, hist))
+}
+
+func (o *Ollama) Complete(ctx context.Context, prompt string) (Reply, error) {
+	params := requestParams(ctx, "ollama", o.model)
+	caps := ModelCapabilities("ollama", o.model)
+	budget := thinkingTokens(params, caps)
+	options := map[string]interface{}{"num_predict": params.MaxTokens + budget}
+	if params.Temperature != nil {
+		options["temperature"] = *params.Temperature
+	}
+	if params.TopP != nil {
+		options["top_p"] = *params.TopP
+	}
+	if len(params.Stop) > 0 {
+		options["stop"] = params.Stop
+	}
+	if params.Seed != nil {
+		options["seed"] = *params.Seed
+	}
+	body := map[string]interface{}{
+		"model": o.model,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+		"stream":  false,
+		"options": options,
+	}
+	if caps.Reasoning {
+		// keeps the thinking out of the answer
+		body["think"] = budget > 0
+	}
+	reqBody, _ := json.Marshal(body)
+
+	req, _ := http.NewRequestWithContext(ctx, "POST", Endpoints["ollama"]+"/api/chat", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return Reply{}, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		PromptEvalCount int    `json:"prompt_eval_count"`
+		EvalCount       int    `json:"eval_count"`
+		DoneReason      string `json:"done_reason"`
+		Message         struct {
+			Content  string `json:"content"`
+			Thinking string `json:"thinking"`
+		} `json:"message"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Reply{}, err
+	}
+	if result.Error != "" {
+		return Reply{}, fmt.Errorf("%s", result.Error)
+	}
+	if resp.StatusCode != 200 {
+		return Reply{}, fmt.Errorf("api error: %s", resp.Status)
+	}
+
+	text, thinking := splitThinking(result.Message.Content)
+	if result.Message.Thinking != "" {
+		thinking = result.Message.Thinking
+	}
+	return Reply{
+		Text:         strings.TrimSpace(text),
+		Reasoning:    strings.TrimSpace(thinking),
+		Usage:        Usage{InputTokens: result.PromptEvalCount, OutputTokens: result.EvalCount},
+		Finish:       classifyFinish(result.DoneReason),
+		FinishReason: result.DoneReason,
+	}, nil
+}
+
+// FetchOllamaModels lists the models pulled on the local server.
+func FetchOllamaModels() ([]string, error) {
+	resp, err := http.Get(Endpoints["ollama"] + "/api/tags")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("api error: %s", resp.Status)
+	}
+
+	var result struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	var models []string
+	for _, m := range result.Models {
+		models = append(models, m.Name)
+	}
+	sort.Strings(models)
+	return models, nil
+}
@@ -2,6 +2,7 @@ package provider
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -33,52 +34,66 @@ func (c *OpenAI) Model() string {
 	return c.model
 }
 
-func (c *OpenAI) GetCommand(userInput, cwd string, shellType shell.ShellType, hist *history.History) (string, error) {
-	prompt := BuildSystemPrompt(userInput, cwd, shellType, hist)
+func (c *OpenAI) GetCommand(ctx context.Context, userInput, cwd string, shellType shell.ShellType, hist *history.History) (Reply, error) {
+	return c.Complete(ctx, BuildSystemPrompt(userInput, cwd, shellType, hist))
+}
 
-	reqBody, _ := json.Marshal(map[string]interface{}{
+func (c *OpenAI) Complete(ctx context.Context, prompt string) (Reply, error) {
+	body := map[string]interface{}{
 		"model": c.model,
 		"messages": []map[string]string{
 			{"role": "user", "content": prompt},
 		},
-		"max_tokens": 300,
-	})
+	}
+	setChatParams(body, requestParams(ctx, "openai", c.model), ModelCapabilities("openai", c.model))
+	reqBody, _ := json.Marshal(body)
 
-	req, _ := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(reqBody))
+	req, _ := http.NewRequestWithContext(ctx, "POST", Endpoints["openai"]+"/v1/chat/completions", bytes.NewBuffer(reqBody))
 	req.Header.Set("Authorization", "Bearer "+c.apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return "", err
+		return Reply{}, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("api error: %s", resp.Status)
+		return Reply{}, fmt.Errorf("api error: %s", resp.Status)
 	}
 
 	var result struct {
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
 		Choices []struct {
 			Message struct {
 				Content string `json:"content"`
 			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+		return Reply{}, err
 	}
 
 	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("no response")
+		return Reply{}, fmt.Errorf("no response")
 	}
 
-	return strings.TrimSpace(result.Choices[0].Message.Content), nil
+	choice := result.Choices[0]
+	return Reply{
+		Text:         strings.TrimSpace(choice.Message.Content),
+		Usage:        Usage{InputTokens: result.Usage.PromptTokens, OutputTokens: result.Usage.CompletionTokens},
+		Finish:       classifyFinish(choice.FinishReason),
+		FinishReason: choice.FinishReason,
+	}, nil
 }
 
 func FetchOpenAIModels(apiKey string) ([]string, error) {
-	req, _ := http.NewRequest("GET", "https://api.openai.com/v1/models", nil)
+	req, _ := http.NewRequest("GET", Endpoints["openai"]+"/v1/models", nil)
 	req.Header.Set("Authorization", "Bearer "+apiKey)
 
 	resp, err := http.DefaultClient.Do(req)
@@ -0,0 +1,53 @@
+package provider
+
+import (
+	"github.com/markymn/nlcli/internal/config"
+)
+
+// defaultMaxTokens leaves room for long pipelines; it used to be 300.
+const defaultMaxTokens = 1024
+
+var generation *config.Generation
+
+// SetGeneration sets the temperature, token limits and other parameters
+// requests are made with.
+func SetGeneration(g *config.Generation) {
+	generation = g
+}
+
+// Params returns the parameters requests to model of the named provider use.
+func Params(name, model string) config.Params {
+	p := generation.For(name, model)
+	if p.MaxTokens == 0 {
+		p.MaxTokens = defaultMaxTokens
+	}
+	return p
+}
+
+// setChatParams adds p to an OpenAI chat completions request for a model
+// with caps.
+func setChatParams(body map[string]interface{}, p config.Params, caps Capabilities) {
+	if caps.Reasoning {
+		// reasoning tokens count against the limit too
+		body["max_completion_tokens"] = p.MaxTokens + thinkingTokens(p, caps)
+	} else {
+		body["max_tokens"] = p.MaxTokens
+	}
+	if caps.Effort {
+		body["reasoning_effort"] = reasoningEffort(p)
+	}
+	if !caps.FixedSampling {
+		if p.Temperature != nil {
+			body["temperature"] = *p.Temperature
+		}
+		if p.TopP != nil {
+			body["top_p"] = *p.TopP
+		}
+		if len(p.Stop) > 0 {
+			body["stop"] = p.Stop
+		}
+	}
+	if p.Seed != nil {
+		body["seed"] = *p.Seed
+	}
+}
@@ -1,22 +1,70 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 	"runtime"
 	"strings"
 
+	"github.com/markymn/nlcli/internal/config"
 	"github.com/markymn/nlcli/internal/history"
+	"github.com/markymn/nlcli/internal/redact"
 	"github.com/markymn/nlcli/internal/shell"
+	"github.com/markymn/nlcli/internal/usage"
 )
 
 type Provider interface {
 	Name() string
 	Model() string
-	GetCommand(userInput, cwd string, shellType shell.ShellType, hist *history.History) (string, error)
+	GetCommand(ctx context.Context, userInput, cwd string, shellType shell.ShellType, hist *history.History) (Reply, error)
+	// Complete sends prompt as is and returns the model's reply.
+	Complete(ctx context.Context, prompt string) (Reply, error)
+}
+
+// Endpoints holds the API base URL of each provider.
+var Endpoints = map[string]string{
+	"openai":    "https://api.openai.com",
+	"anthropic": "https://api.anthropic.com",
+	"google":    "https://generativelanguage.googleapis.com",
+	"groq":      "https://api.groq.com",
+	"ollama":    "http://localhost:11434",
+}
+
+var policy *config.Policy
+
+// SetPolicy restricts which providers and endpoints may be contacted.
+func SetPolicy(p *config.Policy) {
+	policy = p
+}
+
+// Allowed returns an error when the admin policy forbids the provider or its
+// endpoint.
+func Allowed(name string) error {
+	if !policy.AllowsProvider(name) {
+		return fmt.Errorf("%s is not an allowed provider (%s)", GetProviderDisplayName(name), policy.Path)
+	}
+	if !policy.AllowsEndpoint(Endpoints[name]) {
+		return fmt.Errorf("%s is not an allowed endpoint (%s)", Endpoints[name], policy.Path)
+	}
+	return nil
+}
+
+var forbidElevation bool
+var secretNames []string
+
+// SetSecretNames tells the model which {{secret:NAME}} references exist.
+func SetSecretNames(names []string) {
+	secretNames = names
+}
+
+// SetForbidElevation makes the prompt tell the model never to use sudo and
+// other privilege escalation.
+func SetForbidElevation(forbid bool) {
+	forbidElevation = forbid
 }
 
 func BuildSystemPrompt(userInput, cwd string, shellType shell.ShellType, hist *history.History) string {
-	return fmt.Sprintf(`You are a command line expert and translation assistant.
+	prompt := fmt.Sprintf(`You are a command line expert and translation assistant.
 Target Shell: %s
 OS: %s
 Current Directory: %s
@@ -36,8 +84,36 @@ Instructions:
 5. Do NOT recurse through subdirectories unless explicitly requested (words like "recursively", "everywhere", "globally").
 6. Output ONLY the complete, raw command. Do not truncate.`,
 		shell.GetShellName(shellType), runtime.GOOS, cwd, hist.Format(), userInput)
+
+	// the optional rules continue the numbered list
+	rule := 7
+	addRule := func(text string) {
+		prompt += fmt.Sprintf("\n%d. %s", rule, text)
+		rule++
+	}
+	if forbidElevation {
+		addRule("NEVER use sudo, doas, su, pkexec, runas or Start-Process -Verb RunAs. Elevated privileges are not available.")
+	}
+	if len(secretNames) > 0 {
+		addRule("To use one of the user's stored secrets (" + strings.Join(secretNames, ", ") + "), write {{secret:NAME}} in place of its value.")
+	}
+	if redact.HasPlaceholder(prompt) {
+		addRule("Words like REDACTED_SECRET_1 stand for secrets hidden from you. Copy them verbatim where the command needs the value.")
+	}
+	return prompt
 }
 
+// Names lists the supported providers in the order they are offered.
+var Names = []string{"openai", "anthropic", "google", "groq", "ollama"}
+
+// NeedsKey reports whether the provider is used with an API key. Ollama runs
+// locally without one.
+func NeedsKey(name string) bool {
+	return name != "ollama"
+}
+
+// DetectProvider guesses the provider from the key's prefix. It never
+// contacts any API, so an unrecognised key returns "" and the user picks.
 func DetectProvider(apiKey string) (primary string, fallbacks []string) {
 	switch {
 	case strings.HasPrefix(apiKey, "sk-proj-"):
@@ -51,23 +127,14 @@ func DetectProvider(apiKey string) (primary string, fallbacks []string) {
 	case strings.HasPrefix(apiKey, "gsk_"):
 		return "groq", nil
 	default:
-		if provider := VerifyKey(apiKey); provider != "" {
-			return provider, nil
-		}
 		return "", nil
 	}
 }
 
-func VerifyKey(apiKey string) string {
-	providers := []string{"openai", "anthropic", "google", "groq"}
-	for _, p := range providers {
-		if _, err := FetchModels(p, apiKey); err == nil {
-			return p
-		}
-	}
-	return ""
-}
 func FetchModels(providerName, apiKey string) ([]string, error) {
+	if err := Allowed(providerName); err != nil {
+		return nil, err
+	}
 	switch providerName {
 	case "openai":
 		return FetchOpenAIModels(apiKey)
@@ -77,6 +144,8 @@ func FetchModels(providerName, apiKey string) ([]string, error) {
 		return FetchGoogleModels(apiKey)
 	case "groq":
 		return FetchGroqModels(apiKey)
+	case "ollama":
+		return FetchOllamaModels()
 	default:
 		return nil, nil
 	}
@@ -92,6 +161,8 @@ func GetProviderDisplayName(provider string) string {
 		return "Google"
 	case "groq":
 		return "Groq"
+	case "ollama":
+		return "Ollama"
 	default:
 		return provider
 	}
@@ -107,20 +178,38 @@ func GetModels(provider string) []string {
 		return []string{"gemini-2.5-flash", "gemini-2.0-flash", "gemini-1.5-flash", "gemini-1.5-pro"}
 	case "groq":
 		return []string{"llama-3.3-70b-versatile", "llama-3.1-8b-instant", "mixtral-8x7b-32768", "gemma2-9b-it"}
+	case "ollama":
+		return []string{"llama3.2", "qwen2.5-coder", "mistral"}
 	default:
 		return []string{}
 	}
 }
 
 type MultiClient struct {
-	apiKey    string
-	model     string
-	primary   Provider
-	fallbacks []Provider
+	apiKey      string
+	model       string
+	primaryName string
+	primary     Provider
+	fallbacks   []Provider
+	verifier    Provider
+	redacted    int
+	redactor    *redact.Redactor
+
+	strategy     Strategy
+	answered     Provider
+	answeredLink int
+	retried      bool
+	reasoning    string
+	votes        int
+	answers      int
+	usage        Usage
+	ledger       *usage.Ledger
+	budget       *usage.Budget
+	budgetNote   string
 }
 
 func NewMultiClient(apiKey, model, primaryName string, fallbackNames []string) *MultiClient {
-	m := &MultiClient{apiKey: apiKey, model: model}
+	m := &MultiClient{apiKey: apiKey, model: model, primaryName: primaryName}
 
 	m.primary = createProvider(primaryName, apiKey, model)
 
@@ -134,6 +223,9 @@ func NewMultiClient(apiKey, model, primaryName string, fallbackNames []string) *
 }
 
 func createProvider(name, apiKey, model string) Provider {
+	if err := Allowed(name); err != nil {
+		return blocked{name: name, model: model, err: err}
+	}
 	switch name {
 	case "openai":
 		return NewOpenAI(apiKey, model)
@@ -143,25 +235,152 @@ func createProvider(name, apiKey, model string) Provider {
 		return NewGoogle(apiKey, model)
 	case "groq":
 		return NewGroq(apiKey, model)
+	case "ollama":
+		return NewOllama(model)
 	default:
 		return nil
 	}
 }
 
+// blocked stands in for a provider the policy forbids, so every request to
+// it fails instead of reaching the network.
+type blocked struct {
+	name  string
+	model string
+	err   error
+}
+
+func (b blocked) Name() string  { return GetProviderDisplayName(b.name) }
+func (b blocked) Model() string { return b.model }
+
+func (b blocked) GetCommand(context.Context, string, string, shell.ShellType, *history.History) (Reply, error) {
+	return Reply{}, b.err
+}
+
+func (b blocked) Complete(context.Context, string) (Reply, error) {
+	return Reply{}, b.err
+}
+
+// GetCommand redacts secrets from everything it sends. The returned command
+// keeps their placeholders, so it can be shown, stored in the history and
+// audited as is; Restore fills them in for execution.
 func (m *MultiClient) GetCommand(userInput, cwd string, shellType shell.ShellType, hist *history.History) (string, error) {
-	cmd, err := m.primary.GetCommand(userInput, cwd, shellType, hist)
-	if err == nil {
-		return cmd, nil
+	red := redact.New()
+	userInput, cwd, hist = red.Redact(userInput), red.Redact(cwd), hist.Map(red.Redact)
+	m.redacted, m.redactor = red.Count(), red
+
+	ask := m.checked(func(ctx context.Context, p Provider) (Reply, error) {
+		return p.GetCommand(ctx, userInput, cwd, shellType, hist)
+	})
+	m.answered, m.votes, m.answers, m.retried, m.reasoning = nil, 0, 0, false, ""
+	links, err := m.admit()
+	if err != nil {
+		return "", err
 	}
 
-	for _, fb := range m.fallbacks {
-		cmd, err = fb.GetCommand(userInput, cwd, shellType, hist)
-		if err == nil {
-			return cmd, nil
-		}
+	var reply Reply
+	var link int
+	switch {
+	case len(links) == 1 || m.strategy == StrategyFallback:
+		reply, link, err = m.sequential(links, ask)
+	case m.strategy == StrategyRace:
+		reply, link, err = m.concurrent(links, 1, ask)
+	default:
+		reply, link, err = m.concurrent(links, len(links)/2+1, ask)
+	}
+	if err != nil {
+		return "", err
+	}
+	m.answered, m.answeredLink, m.usage, m.retried = links[link], link, reply.Usage, reply.Retried
+	m.reasoning = reply.Reasoning
+	return reply.Text, nil
+}
+
+// Restore puts the secrets hidden by the last GetCommand or Compare back into
+// cmd. Only the command that is executed should be restored.
+func (m *MultiClient) Restore(cmd string) string {
+	if m.redactor == nil {
+		return cmd
 	}
+	return m.redactor.Restore(cmd)
+}
+
+// SetLedger makes every request count towards l.
+func (m *MultiClient) SetLedger(l *usage.Ledger) {
+	m.ledger = l
+}
+
+// record adds a reply's usage to the ledger. Write errors surface through
+// the ledger's Err.
+func (m *MultiClient) record(p Provider, u Usage) {
+	if m.ledger != nil {
+		m.ledger.Add(p.Name(), p.Model(), u.InputTokens, u.OutputTokens)
+	}
+}
+
+// Usage returns the tokens used by the reply GetCommand last returned.
+func (m *MultiClient) Usage() Usage {
+	return m.usage
+}
+
+// Reasoning returns the thinking behind the last command, when the model
+// returned it. Redacted secrets stay placeholders.
+func (m *MultiClient) Reasoning() string {
+	return m.reasoning
+}
+
+// Redacted returns how many secrets the last GetCommand kept from the model.
+func (m *MultiClient) Redacted() int {
+	return m.redacted
+}
+
+// SetVerifier makes Verify ask model, from the primary's provider, to review
+// each command. An empty model turns verification off.
+func (m *MultiClient) SetVerifier(model string) {
+	m.verifier = nil
+	if model != "" {
+		m.verifier = createProvider(m.primaryName, m.apiKey, model)
+	}
+}
+
+func (m *MultiClient) VerifierModel() string {
+	if m.verifier == nil {
+		return ""
+	}
+	return m.verifier.Model()
+}
+
+// Verify asks the verifier whether cmd does what userInput asked for. It
+// returns nil when no verifier is set.
+func (m *MultiClient) Verify(userInput, cmd string, shellType shell.ShellType) (*Review, error) {
+	if m.verifier == nil {
+		return nil, nil
+	}
+	if _, err := m.admit(); err != nil {
+		return nil, err
+	}
+	red := redact.New()
+	prompt := BuildVerifyPrompt(red.Redact(userInput), red.Redact(cmd), shellType)
+	reply, err := m.checked(func(ctx context.Context, p Provider) (Reply, error) {
+		return p.Complete(ctx, prompt)
+	})(context.Background(), m.verifier)
+	if err == nil || reply.Usage.Total() > 0 {
+		m.record(m.verifier, reply.Usage)
+	}
+	if err != nil {
+		return nil, err
+	}
+	review, err := parseReview(reply.Text)
+	if err != nil {
+		return nil, err
+	}
+	review.Model = m.verifier.Model()
+	return review, nil
+}
 
-	return "", err
+// ProviderName returns the primary provider's name as used in Names.
+func (m *MultiClient) ProviderName() string {
+	return m.primaryName
 }
 
 func (m *MultiClient) PrimaryName() string {
@@ -1,7 +1,15 @@
 package provider
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/markymn/nlcli/internal/config"
+	"github.com/markymn/nlcli/internal/history"
+	"github.com/markymn/nlcli/internal/shell"
 )
 
 func TestDetectProvider(t *testing.T) {
@@ -61,3 +69,410 @@ func TestDetectProvider(t *testing.T) {
 		})
 	}
 }
+
+func TestParseReview(t *testing.T) {
+	tests := []struct {
+		reply       string
+		matches     bool
+		destructive bool
+		reason      string
+		wantErr     bool
+	}{
+		{reply: "MATCH: yes\nDESTRUCTIVE: no\nREASON: lists files as asked", matches: true, reason: "lists files as asked"},
+		{reply: "**MATCH:** No\n**DESTRUCTIVE:** Yes\n**REASON:** deletes files instead of listing them",
+			destructive: true, reason: "deletes files instead of listing them"},
+		{reply: "match: yes.\ndestructive: yes", matches: true, destructive: true},
+		{reply: "Looks fine to me.", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseReview(tt.reply)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseReview(%q) succeeded, want error", tt.reply)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("parseReview(%q) error = %v", tt.reply, err)
+		}
+		if got.Matches != tt.matches || got.Destructive != tt.destructive || got.Reason != tt.reason {
+			t.Errorf("parseReview(%q) = %+v", tt.reply, got)
+		}
+	}
+}
+
+type recordingProvider struct {
+	input string
+	hist  string
+	reply string
+}
+
+func (p *recordingProvider) Name() string  { return "Fake" }
+func (p *recordingProvider) Model() string { return "fake-1" }
+
+func (p *recordingProvider) GetCommand(ctx context.Context, userInput, cwd string, shellType shell.ShellType, hist *history.History) (Reply, error) {
+	p.input, p.hist = userInput, hist.Format()
+	return Reply{Text: p.reply}, nil
+}
+
+func (p *recordingProvider) Complete(ctx context.Context, prompt string) (Reply, error) {
+	return Reply{Text: p.reply}, nil
+}
+
+func TestMultiClientRedacts(t *testing.T) {
+	secret := "sk-proj-abcdefghijklmnopqrstuvwxyz"
+	hist := history.New()
+	hist.Add("cat .env", "OPENAI_API_KEY="+secret+"\nDB_PASSWORD=hunter2hunter2")
+
+	fake := &recordingProvider{reply: "curl -H 'Authorization: Bearer REDACTED_SECRET_1' https://api.openai.com/v1/models"}
+	m := &MultiClient{primary: fake}
+
+	cmd, err := m.GetCommand("list models using key "+secret, "/tmp", shell.ShellBash, hist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(fake.input, secret) || strings.Contains(fake.hist, secret) || strings.Contains(fake.hist, "hunter2hunter2") {
+		t.Errorf("secret sent to provider: input %q, history %q", fake.input, fake.hist)
+	}
+	if strings.Contains(cmd, secret) {
+		t.Errorf("GetCommand() = %q, want the placeholder kept", cmd)
+	}
+	if got := m.Restore(cmd); !strings.Contains(got, "Bearer "+secret) {
+		t.Errorf("Restore() = %q, want the secret restored", got)
+	}
+	if m.Redacted() != 2 {
+		t.Errorf("Redacted() = %d, want 2", m.Redacted())
+	}
+}
+
+func TestBuildSystemPromptRules(t *testing.T) {
+	defer SetForbidElevation(false)
+	defer SetSecretNames(nil)
+
+	tests := []struct {
+		name    string
+		forbid  bool
+		secrets []string
+		input   string
+		want    []string
+	}{
+		{name: "none", input: "list files", want: []string{"6. Output ONLY"}},
+		{name: "secrets", secrets: []string{"TOKEN"}, input: "push", want: []string{"7. To use one of the user's stored secrets (TOKEN)"}},
+		{
+			name:    "all",
+			forbid:  true,
+			secrets: []string{"TOKEN"},
+			input:   "call the api with REDACTED_SECRET_1",
+			want:    []string{"7. NEVER use sudo", "8. To use one", "9. Words like REDACTED_SECRET_1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetForbidElevation(tt.forbid)
+			SetSecretNames(tt.secrets)
+			prompt := BuildSystemPrompt(tt.input, "/tmp", shell.ShellBash, history.New())
+			for _, w := range tt.want {
+				if !strings.Contains(prompt, "\n"+w) {
+					t.Errorf("prompt lacks rule %q:\n%s", w, prompt)
+				}
+			}
+			if strings.Contains(prompt, "\n- ") {
+				t.Errorf("prompt has unnumbered rules:\n%s", prompt)
+			}
+		})
+	}
+}
+
+type failingProvider struct{ name string }
+
+func (p failingProvider) Name() string  { return p.name }
+func (p failingProvider) Model() string { return "down-1" }
+
+func (p failingProvider) GetCommand(context.Context, string, string, shell.ShellType, *history.History) (Reply, error) {
+	return Reply{}, errors.New("503 Service Unavailable")
+}
+
+func (p failingProvider) Complete(context.Context, string) (Reply, error) {
+	return Reply{}, errors.New("503 Service Unavailable")
+}
+
+func TestMultiClientChain(t *testing.T) {
+	fake := &recordingProvider{reply: "ls -la"}
+	m := &MultiClient{primary: failingProvider{"Groq"}, fallbacks: []Provider{failingProvider{"OpenAI"}, fake}}
+
+	cmd, err := m.GetCommand("list files", "/tmp", shell.ShellBash, history.New())
+	if err != nil || cmd != "ls -la" {
+		t.Fatalf("GetCommand() = %q, %v", cmd, err)
+	}
+	if p, link := m.Answered(); p != fake || link != 2 {
+		t.Errorf("Answered() = %v, %d; want the third link", p, link)
+	}
+
+	m.fallbacks = m.fallbacks[:1]
+	_, err = m.GetCommand("list files", "/tmp", shell.ShellBash, history.New())
+	if err == nil || !strings.Contains(err.Error(), "Groq") || !strings.Contains(err.Error(), "OpenAI") {
+		t.Errorf("GetCommand() error = %v, want both failures", err)
+	}
+	if p, _ := m.Answered(); p != nil {
+		t.Errorf("Answered() = %v after every link failed", p)
+	}
+}
+
+func TestParseLink(t *testing.T) {
+	tests := []struct {
+		entry, name, model string
+		wantErr            bool
+	}{
+		{entry: "groq/llama-3.1-8b-instant", name: "groq", model: "llama-3.1-8b-instant"},
+		{entry: "OpenAI", name: "openai"},
+		{entry: "groq/meta-llama/llama-4-scout-17b-16e-instruct", name: "groq", model: "meta-llama/llama-4-scout-17b-16e-instruct"},
+		{entry: "ollama/llama3.2", name: "ollama", model: "llama3.2"},
+		{entry: "mistral/large", wantErr: true},
+	}
+	for _, tt := range tests {
+		name, model, err := ParseLink(tt.entry)
+		if (err != nil) != tt.wantErr || name != tt.name || model != tt.model {
+			t.Errorf("ParseLink(%q) = %q, %q, %v", tt.entry, name, model, err)
+		}
+	}
+}
+
+// slowProvider answers after delay unless the request is cancelled first.
+type slowProvider struct {
+	name      string
+	reply     string
+	delay     time.Duration
+	cancelled chan bool
+}
+
+func (p *slowProvider) Name() string  { return p.name }
+func (p *slowProvider) Model() string { return "slow-1" }
+
+func (p *slowProvider) GetCommand(ctx context.Context, _, _ string, _ shell.ShellType, _ *history.History) (Reply, error) {
+	return p.Complete(ctx, "")
+}
+
+func (p *slowProvider) Complete(ctx context.Context, _ string) (Reply, error) {
+	select {
+	case <-time.After(p.delay):
+		return Reply{Text: p.reply}, nil
+	case <-ctx.Done():
+		p.cancelled <- true
+		return Reply{}, ctx.Err()
+	}
+}
+
+func TestMultiClientStrategies(t *testing.T) {
+	cancelled := make(chan bool, 4)
+	slow := func(name, reply string, delay time.Duration) *slowProvider {
+		return &slowProvider{name: name, reply: reply, delay: delay * time.Millisecond, cancelled: cancelled}
+	}
+
+	tests := []struct {
+		name      string
+		strategy  Strategy
+		links     []Provider
+		want      string
+		wantLink  int
+		cancelled int
+	}{
+		{name: "race takes the fastest", strategy: StrategyRace,
+			links: []Provider{slow("A", "ls -l", 2000), slow("B", "ls", 10), failingProvider{"C"}},
+			want:  "ls", wantLink: 1, cancelled: 1},
+		{name: "race skips empty replies", strategy: StrategyRace,
+			links: []Provider{slow("A", "ls -l", 50), slow("B", " ", 10)},
+			want:  "ls -l", wantLink: 0},
+		{name: "quorum waits for agreement", strategy: StrategyQuorum,
+			links: []Provider{slow("A", "ls  -la", 50), slow("B", "ls -la", 10), slow("C", "dir", 2000)},
+			want:  "ls  -la", wantLink: 0, cancelled: 1},
+		{name: "quorum falls back to the most common", strategy: StrategyQuorum,
+			links: []Provider{failingProvider{"A"}, slow("B", "ls -a", 30), slow("C", "ls -la", 10)},
+			want:  "ls -a", wantLink: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MultiClient{primary: tt.links[0], fallbacks: tt.links[1:], strategy: tt.strategy}
+			start := time.Now()
+			cmd, err := m.GetCommand("list files", "/tmp", shell.ShellBash, history.New())
+			if err != nil || cmd != tt.want {
+				t.Fatalf("GetCommand() = %q, %v; want %q", cmd, err, tt.want)
+			}
+			if _, link := m.Answered(); link != tt.wantLink {
+				t.Errorf("Answered() link = %d, want %d", link, tt.wantLink)
+			}
+			if time.Since(start) > time.Second {
+				t.Errorf("GetCommand() waited for the slow provider")
+			}
+			for i := 0; i < tt.cancelled; i++ {
+				select {
+				case <-cancelled:
+				case <-time.After(time.Second):
+					t.Fatal("slow request was not cancelled")
+				}
+			}
+		})
+	}
+}
+
+func TestMultiClientCompare(t *testing.T) {
+	fast := &recordingProvider{reply: "ls -la"}
+	m := &MultiClient{primary: fast, fallbacks: []Provider{failingProvider{"Groq"}, fast}}
+
+	results, err := m.Compare(nil, "list files", "/tmp", shell.ShellBash, history.New())
+	if err != nil || len(results) != 2 {
+		t.Fatalf("Compare() returned %d results, want the duplicate dropped: %+v", len(results), results)
+	}
+	if results[0].Provider != "Fake" || results[0].Command != "ls -la" || results[0].Err != nil {
+		t.Errorf("results[0] = %+v", results[0])
+	}
+	if results[1].Provider != "Groq" || results[1].Err == nil {
+		t.Errorf("results[1] = %+v, want the failure", results[1])
+	}
+}
+
+func TestSetChatParams(t *testing.T) {
+	temp, budget := 0.2, 2000
+	p := config.Params{MaxTokens: 1024, Temperature: &temp, Stop: []string{"\n\n"}, ThinkingBudget: &budget}
+
+	body := map[string]interface{}{}
+	setChatParams(body, p, ModelCapabilities("openai", "gpt-4o"))
+	if body["max_tokens"] != 1024 || body["temperature"] != 0.2 || body["stop"] == nil || body["max_completion_tokens"] != nil {
+		t.Errorf("chat model body = %v", body)
+	}
+
+	body = map[string]interface{}{}
+	setChatParams(body, p, ModelCapabilities("openai", "o3-mini"))
+	if body["max_completion_tokens"] != 3024 || body["max_tokens"] != nil || body["temperature"] != nil || body["stop"] != nil || body["reasoning_effort"] != "low" {
+		t.Errorf("reasoning model body = %v", body)
+	}
+}
+
+func TestClassifyFinish(t *testing.T) {
+	tests := []struct {
+		reason string
+		want   Finish
+	}{
+		{"stop", FinishComplete},
+		{"end_turn", FinishComplete},
+		{"STOP", FinishComplete},
+		{"", FinishComplete},
+		{"length", FinishLength},
+		{"max_tokens", FinishLength},
+		{"MAX_TOKENS", FinishLength},
+		{"content_filter", FinishFiltered},
+		{"refusal", FinishFiltered},
+		{"SAFETY", FinishFiltered},
+		{"RECITATION", FinishFiltered},
+	}
+	for _, tt := range tests {
+		if got := classifyFinish(tt.reason); got != tt.want {
+			t.Errorf("classifyFinish(%q) = %d, want %d", tt.reason, got, tt.want)
+		}
+	}
+}
+
+// truncatingProvider cuts its reply off unless the token limit was raised at
+// least scale times, and counts the requests it gets.
+type truncatingProvider struct {
+	scale    int
+	filtered bool
+	calls    int
+}
+
+func (p *truncatingProvider) Name() string  { return "Truncating" }
+func (p *truncatingProvider) Model() string { return "short-1" }
+
+func (p *truncatingProvider) GetCommand(ctx context.Context, _, _ string, _ shell.ShellType, _ *history.History) (Reply, error) {
+	return p.Complete(ctx, "")
+}
+
+func (p *truncatingProvider) Complete(ctx context.Context, _ string) (Reply, error) {
+	p.calls++
+	if p.filtered {
+		return Reply{Finish: FinishFiltered, FinishReason: "SAFETY"}, nil
+	}
+	if requestParams(ctx, "openai", "gpt-4o").MaxTokens < p.scale*defaultMaxTokens {
+		return Reply{Text: "find . -name", Finish: FinishLength, FinishReason: "length"}, nil
+	}
+	return Reply{Text: "find . -name '*.go'", Finish: FinishComplete, FinishReason: "stop"}, nil
+}
+
+func TestMultiClientTruncation(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider *truncatingProvider
+		want     string
+		wantErr  string
+		calls    int
+	}{
+		{"complete", &truncatingProvider{scale: 1}, "find . -name '*.go'", "", 1},
+		{"retried", &truncatingProvider{scale: retryScale}, "find . -name '*.go'", "", 2},
+		{"still cut off", &truncatingProvider{scale: retryScale * 2}, "", "cut off", 2},
+		{"filtered", &truncatingProvider{filtered: true}, "", "safety filter", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MultiClient{primary: tt.provider}
+			cmd, err := m.GetCommand("find go files", "/tmp", shell.ShellBash, history.New())
+			if cmd != tt.want || (err == nil) != (tt.wantErr == "") || err != nil && !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("GetCommand() = %q, %v; want %q, error containing %q", cmd, err, tt.want, tt.wantErr)
+			}
+			if tt.provider.calls != tt.calls {
+				t.Errorf("provider asked %d times, want %d", tt.provider.calls, tt.calls)
+			}
+			if m.Retried() != (tt.calls == 2 && err == nil) {
+				t.Errorf("Retried() = %v", m.Retried())
+			}
+		})
+	}
+}
+
+func TestThinkingTokens(t *testing.T) {
+	zero, small, big := 0, 100, 8000
+	tests := []struct {
+		provider, model string
+		budget          *int
+		want            int
+	}{
+		{"openai", "gpt-4o", &big, 0},
+		{"openai", "o3-mini", nil, defaultThinkingTokens},
+		{"openai", "o3-mini", &zero, defaultThinkingTokens},
+		{"openai", "gpt-5-chat-latest", nil, 0},
+		{"anthropic", "claude-3-haiku-20240307", &big, 0},
+		{"anthropic", "claude-sonnet-4-20250514", nil, 0},
+		{"anthropic", "claude-sonnet-4-20250514", &small, 1024},
+		{"anthropic", "claude-opus-4-1", &big, 8000},
+		{"google", "gemini-2.0-flash", &big, 0},
+		{"google", "gemini-2.5-flash", nil, defaultThinkingTokens},
+		{"google", "gemini-2.5-flash", &zero, 0},
+		{"google", "gemini-2.5-pro", &zero, 128},
+		{"google", "gemini-2.5-flash-lite", nil, 0},
+		{"groq", "qwen/qwen3-32b", &zero, 0},
+		{"groq", "llama-3.3-70b-versatile", nil, 0},
+		{"ollama", "deepseek-r1:8b", nil, defaultThinkingTokens},
+	}
+	for _, tt := range tests {
+		p := config.Params{ThinkingBudget: tt.budget}
+		if got := thinkingTokens(p, ModelCapabilities(tt.provider, tt.model)); got != tt.want {
+			t.Errorf("thinkingTokens(%s/%s, %v) = %d, want %d", tt.provider, tt.model, tt.budget, got, tt.want)
+		}
+	}
+}
+
+func TestSplitThinking(t *testing.T) {
+	tests := []struct {
+		text, answer, thinking string
+	}{
+		{"ls -la", "ls -la", ""},
+		{"<think>\nthe user wants files\n</think>\n\nls -la", "ls -la", "the user wants files"},
+		{"<think>still going", "", "still going"},
+	}
+	for _, tt := range tests {
+		answer, thinking := splitThinking(tt.text)
+		if answer != tt.answer || thinking != tt.thinking {
+			t.Errorf("splitThinking(%q) = %q, %q", tt.text, answer, thinking)
+		}
+	}
+}
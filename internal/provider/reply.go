@@ -0,0 +1,31 @@
+package provider
+
+// Reply is a model's answer together with what the API reported about it.
+type Reply struct {
+	Text  string
+	Usage Usage
+	// Finish and FinishReason say why generation stopped; FinishReason is
+	// the provider's own wording.
+	Finish       Finish
+	FinishReason string
+	// Reasoning is the thinking or its summary, for models that return it.
+	Reasoning string
+	// Retried is set when the reply was asked for again with a larger
+	// token limit.
+	Retried bool
+}
+
+// Usage counts the tokens a request consumed, as reported by the provider.
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+}
+
+func (u Usage) Total() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+func (u *Usage) Add(other Usage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+}
@@ -0,0 +1,164 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Strategy decides how MultiClient uses its chain of providers.
+type Strategy int
+
+const (
+	// StrategyFallback asks one provider at a time, moving on when it fails.
+	StrategyFallback Strategy = iota
+	// StrategyRace asks all of them at once and takes the first answer.
+	StrategyRace
+	// StrategyQuorum asks all of them at once and takes the first command a
+	// majority agrees on, or the most common one when none reaches it.
+	StrategyQuorum
+)
+
+func (s Strategy) String() string {
+	switch s {
+	case StrategyRace:
+		return "race"
+	case StrategyQuorum:
+		return "quorum"
+	default:
+		return "fallback"
+	}
+}
+
+func ParseStrategy(s string) (Strategy, bool) {
+	switch strings.ToLower(s) {
+	case "fallback":
+		return StrategyFallback, true
+	case "race":
+		return StrategyRace, true
+	case "quorum":
+		return StrategyQuorum, true
+	}
+	return StrategyFallback, false
+}
+
+func (m *MultiClient) SetStrategy(s Strategy) {
+	m.strategy = s
+}
+
+func (m *MultiClient) Strategy() Strategy {
+	return m.strategy
+}
+
+// Agreement returns how many providers returned the last command under the
+// quorum strategy, out of how many answered.
+func (m *MultiClient) Agreement() (votes, answers int) {
+	return m.votes, m.answers
+}
+
+type askFunc func(ctx context.Context, p Provider) (Reply, error)
+
+// answer is one link's reply to a concurrent request.
+type answer struct {
+	link  int
+	reply Reply
+	err   error
+}
+
+// sequential asks each link in turn until one answers.
+func (m *MultiClient) sequential(links []Provider, ask askFunc) (Reply, int, error) {
+	var failures []string
+	var err error
+	for i, p := range links {
+		var reply Reply
+		reply, err = ask(context.Background(), p)
+		if err == nil || reply.Usage.Total() > 0 {
+			m.record(p, reply.Usage)
+		}
+		if err == nil {
+			return reply, i, nil
+		}
+		failures = append(failures, fmt.Sprintf("%s (%s): %s", p.Name(), p.Model(), err))
+	}
+	if len(failures) == 1 {
+		return Reply{}, 0, err
+	}
+	return Reply{}, 0, fmt.Errorf("every provider in the chain failed: %s", strings.Join(failures, "; "))
+}
+
+// concurrent asks every link at once and returns as soon as quorum of them
+// agree on a command, cancelling the requests still running. Empty replies
+// count as failures. Cancelled requests were still sent, so their usage is
+// recorded too: what the provider reported, or else the winner's prompt
+// tokens, since every link got the same prompt.
+func (m *MultiClient) concurrent(links []Provider, quorum int, ask askFunc) (Reply, int, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	answers := make(chan answer, len(links))
+	for i, p := range links {
+		go func() {
+			reply, err := ask(ctx, p)
+			if err == nil && strings.TrimSpace(reply.Text) == "" {
+				err = fmt.Errorf("empty reply")
+			}
+			answers <- answer{i, reply, err}
+		}()
+	}
+
+	// first[k] is the earliest link that returned the command normalised to k
+	votes := make(map[string]int)
+	first := make(map[string]answer)
+	failures := make([]string, 0, len(links))
+	for pending := len(links); pending > 0; pending-- {
+		a := <-answers
+		if a.err == nil || a.reply.Usage.Total() > 0 {
+			m.record(links[a.link], a.reply.Usage)
+		}
+		if a.err != nil {
+			p := links[a.link]
+			failures = append(failures, fmt.Sprintf("%s (%s): %s", p.Name(), p.Model(), a.err))
+			continue
+		}
+		m.answers++
+		key := strings.Join(strings.Fields(a.reply.Text), " ")
+		votes[key]++
+		if f, ok := first[key]; !ok || a.link < f.link {
+			first[key] = a
+		}
+		if votes[key] >= quorum {
+			m.votes = votes[key]
+			cancel()
+			m.recordCancelled(links, answers, pending-1, first[key].reply.Usage.InputTokens)
+			return first[key].reply, first[key].link, nil
+		}
+	}
+
+	if m.answers == 0 {
+		return Reply{}, 0, fmt.Errorf("every provider failed: %s", strings.Join(failures, "; "))
+	}
+	best := answer{link: len(links)}
+	for key, a := range first {
+		if votes[key] > m.votes || votes[key] == m.votes && a.link < best.link {
+			best, m.votes = a, votes[key]
+		}
+	}
+	return best.reply, best.link, nil
+}
+
+// recordCancelled waits for the n requests still running after cancellation
+// and records their usage, estimating input tokens for those cut off without
+// a report.
+func (m *MultiClient) recordCancelled(links []Provider, answers <-chan answer, n, input int) {
+	for range n {
+		a := <-answers
+		u := a.reply.Usage
+		if u.Total() == 0 && errors.Is(a.err, context.Canceled) {
+			u.InputTokens = input
+		}
+		if a.err == nil || u.Total() > 0 {
+			m.record(links[a.link], u)
+		}
+	}
+}
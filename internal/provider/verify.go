@@ -0,0 +1,58 @@
+package provider
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/markymn/nlcli/internal/shell"
+)
+
+// Review is a second model's opinion of a translated command.
+type Review struct {
+	Model       string
+	Matches     bool
+	Destructive bool
+	Reason      string
+}
+
+func BuildVerifyPrompt(userInput, cmd string, shellType shell.ShellType) string {
+	return fmt.Sprintf(`You are reviewing a shell command another assistant wrote for a user.
+Target Shell: %s
+OS: %s
+
+User Request: %s
+Proposed Command: %s
+
+Answer in exactly this format, with no other text:
+MATCH: yes or no (does the command do what the user asked, and nothing more?)
+DESTRUCTIVE: yes or no (does it delete, overwrite or change files, settings or system state?)
+REASON: one short sentence explaining any mismatch or damage`,
+		shell.GetShellName(shellType), runtime.GOOS, userInput, cmd)
+}
+
+func parseReview(reply string) (*Review, error) {
+	r := &Review{}
+	seenMatch := false
+	for _, line := range strings.Split(reply, "\n") {
+		key, value, ok := strings.Cut(strings.Trim(strings.TrimSpace(line), "*"), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(strings.Trim(strings.TrimSpace(value), "*"))
+		yes := strings.HasPrefix(strings.ToLower(value), "yes")
+		switch strings.ToUpper(strings.TrimSpace(key)) {
+		case "MATCH":
+			r.Matches = yes
+			seenMatch = true
+		case "DESTRUCTIVE":
+			r.Destructive = yes
+		case "REASON":
+			r.Reason = value
+		}
+	}
+	if !seenMatch {
+		return nil, fmt.Errorf("unexpected verifier reply: %q", reply)
+	}
+	return r, nil
+}
@@ -0,0 +1,38 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/markymn/nlcli/internal/audit"
+)
+
+func (r *REPL) audit(rec audit.Record) {
+	if !r.auditLog {
+		return
+	}
+	if rec.Cwd == "" {
+		rec.Cwd, _ = os.Getwd()
+	}
+	if err := audit.Append(rec); err != nil {
+		if r.policy != nil && r.policy.RequireAudit {
+			fmt.Printf("%sError: could not write audit log, which %s requires: %s%s\n", colorRed, r.policy.Path, err, colorReset)
+			os.Exit(1)
+		}
+		fmt.Printf("%sWarning: could not write audit log: %s%s\n", colorYellow, err, colorReset)
+	}
+}
+
+func (r *REPL) handleAudit(args []string) {
+	if len(args) == 0 || args[0] != "verify" {
+		fmt.Printf("%sUsage: .audit verify%s\n", colorRed, colorReset)
+		return
+	}
+
+	n, err := audit.Verify()
+	if err != nil {
+		fmt.Printf("%sAudit log FAILED after %d valid records: %s%s\n", colorRed, n, err, colorReset)
+		return
+	}
+	fmt.Printf("Audit log OK (%d records): %s\n", n, audit.Path())
+}
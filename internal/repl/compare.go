@@ -0,0 +1,86 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/markymn/nlcli/internal/audit"
+	"github.com/markymn/nlcli/internal/config"
+	"github.com/markymn/nlcli/internal/provider"
+)
+
+// compare sends input to every configured provider/model at once, shows
+// their commands side by side and runs the one the user picks.
+func (r *REPL) compare(input string) {
+	if input == "" {
+		fmt.Printf("%sUsage: .compare <request>%s\n", colorRed, colorReset)
+		return
+	}
+
+	cwd, _ := os.Getwd()
+	fmt.Println("Asking every configured provider...")
+	results, err := r.client.Compare(r.compareLinks(), input, cwd, r.shellType, r.history)
+	r.checkLedger()
+	if err != nil {
+		fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+		return
+	}
+	r.showBudgetNote()
+
+	fmt.Printf("\n  %-3s %-28s %8s %12s  %s\n", "#", "Provider / model", "Time", "Tokens", "Command")
+	for i, c := range results {
+		tokens := "-"
+		if c.Usage.Total() > 0 {
+			tokens = fmt.Sprintf("%d/%d", c.Usage.InputTokens, c.Usage.OutputTokens)
+		}
+		name := c.Provider + " " + c.Model
+		if len(name) > 28 {
+			name = name[:27] + "…"
+		}
+		command := colorYellow + cleanCommand(c.Command) + colorReset
+		if c.Err != nil {
+			command = colorRed + c.Err.Error() + colorReset
+		}
+		fmt.Printf("  %-3d %-28s %8s %12s  %s\n", i+1, name, c.Latency.Round(time.Millisecond), tokens, command)
+	}
+	fmt.Println("  (tokens are input/output as reported by each provider)")
+
+	fmt.Printf("\nRun which one? [1-%d, Enter to skip]: ", len(results))
+	answer, _ := r.reader.ReadString('\n')
+	n, err := strconv.Atoi(strings.TrimSpace(answer))
+	if err != nil || n < 1 || n > len(results) || results[n-1].Err != nil || cleanCommand(results[n-1].Command) == "" {
+		fmt.Println("Skipped.")
+		return
+	}
+
+	c := results[n-1]
+	rec := audit.Record{Kind: "compare", Input: input, Provider: c.Provider, Model: c.Model, Cwd: cwd}
+	r.runTranslated(input, cleanCommand(c.Command), &rec)
+	r.audit(rec)
+}
+
+// compareLinks adds the stored providers, at the model last used with each,
+// to the active provider and its fallback chain.
+func (r *REPL) compareLinks() []provider.Link {
+	names, err := config.StoredProviders()
+	if err != nil {
+		fmt.Printf("%sWarning: %s%s\n", colorYellow, err, colorReset)
+		return nil
+	}
+	var links []provider.Link
+	for _, name := range names {
+		if provider.Allowed(name) != nil {
+			continue
+		}
+		key, err := config.LoadProviderKey(name)
+		if err != nil {
+			continue
+		}
+		model, _ := config.LoadProviderModel(name)
+		links = append(links, provider.Link{Provider: name, Model: model, APIKey: key})
+	}
+	return links
+}
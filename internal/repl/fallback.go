@@ -0,0 +1,121 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/markymn/nlcli/internal/config"
+	"github.com/markymn/nlcli/internal/provider"
+)
+
+// chainLinks builds the configured fallback chain, skipping entries that
+// cannot be used and the active provider itself.
+func (r *REPL) chainLinks(entries []string) []provider.Link {
+	var links []provider.Link
+	for _, entry := range entries {
+		name, model, err := provider.ParseLink(entry)
+		if err != nil {
+			fmt.Printf("%sWarning: fallback chain: %s%s\n", colorYellow, err, colorReset)
+			continue
+		}
+		if name == r.client.ProviderName() && (model == "" || model == r.client.PrimaryModel()) {
+			continue
+		}
+		link := provider.Link{Provider: name, Model: model}
+		if provider.NeedsKey(name) {
+			if link.APIKey, err = config.LoadProviderKey(name); err != nil {
+				fmt.Printf("%sWarning: fallback chain: skipping %s: %s%s\n", colorYellow, entry, err, colorReset)
+				continue
+			}
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+// applyChain replaces the fallbacks guessed from the key with the configured
+// chain, if there is one, and sets how the chain is used.
+func (r *REPL) applyChain() {
+	if entries := config.LoadFallbackChain(); len(entries) > 0 {
+		r.client.SetChain(r.chainLinks(entries))
+	}
+	strategy, _ := provider.ParseStrategy(config.LoadStrategy())
+	r.client.SetStrategy(strategy)
+}
+
+// changeFallback shows or sets the chain tried when the active provider
+// fails: .fallback groq/llama-3.1-8b-instant openai/gpt-4o-mini ollama/llama3.2
+func (r *REPL) changeFallback(args []string) {
+	if len(args) == 0 {
+		entries := config.LoadFallbackChain()
+		if len(entries) == 0 {
+			fmt.Println("No fallback chain configured.")
+		} else {
+			fmt.Printf("Fallback chain: %s%s -> %s%s\n", colorYellow, r.client.PrimaryName(), strings.Join(entries, " -> "), colorReset)
+		}
+		fmt.Println("Usage: .fallback <provider>[/<model>] ... | .fallback off")
+		return
+	}
+
+	var entries []string
+	if !(len(args) == 1 && strings.EqualFold(args[0], "off")) {
+		for _, arg := range args {
+			if _, _, err := provider.ParseLink(arg); err != nil {
+				fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+				return
+			}
+			entries = append(entries, arg)
+		}
+	}
+	if err := config.SaveFallbackChain(entries); err != nil {
+		fmt.Printf("%sError saving config: %s%s\n", colorRed, err, colorReset)
+		return
+	}
+	r.client.SetChain(r.chainLinks(entries))
+	r.changeFallback(nil)
+}
+
+// changeStrategy picks whether the chain is tried in order, raced, or asked
+// for a majority answer.
+func (r *REPL) changeStrategy(args []string) {
+	if len(args) == 0 {
+		fmt.Printf("Strategy: %s%s%s (%d provider(s) in the chain)\n", colorYellow, r.client.Strategy(), colorReset, r.client.ChainLength())
+		fmt.Println("Usage: .strategy <fallback|race|quorum>")
+		return
+	}
+
+	strategy, ok := provider.ParseStrategy(args[0])
+	if !ok {
+		fmt.Printf("%sUsage: .strategy <fallback|race|quorum>%s\n", colorRed, colorReset)
+		return
+	}
+	if err := config.SaveStrategy(strategy.String()); err != nil {
+		fmt.Printf("%sError saving config: %s%s\n", colorRed, err, colorReset)
+		return
+	}
+	r.client.SetStrategy(strategy)
+	r.changeStrategy(nil)
+	if strategy != provider.StrategyFallback && r.client.ChainLength() == 1 {
+		fmt.Println("Add providers with .fallback for this to take effect.")
+	}
+}
+
+// showAnswered notes which link of the chain produced the command, when it
+// is not simply the active provider.
+func (r *REPL) showAnswered() {
+	p, link := r.client.Answered()
+	if p == nil || r.client.ChainLength() == 1 {
+		return
+	}
+	switch r.client.Strategy() {
+	case provider.StrategyRace:
+		fmt.Printf("  (fastest answer: %s %s)\n", p.Name(), p.Model())
+	case provider.StrategyQuorum:
+		votes, answers := r.client.Agreement()
+		fmt.Printf("  (%s %s; %d of %d answers agreed)\n", p.Name(), p.Model(), votes, answers)
+	default:
+		if link > 0 {
+			fmt.Printf("  (answered by %s %s, link %d of %d in the fallback chain)\n", p.Name(), p.Model(), link+1, r.client.ChainLength())
+		}
+	}
+}
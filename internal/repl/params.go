@@ -0,0 +1,50 @@
+package repl
+
+import (
+	"fmt"
+
+	"github.com/markymn/nlcli/internal/config"
+	"github.com/markymn/nlcli/internal/provider"
+)
+
+// loadGeneration reads generation.toml for the providers.
+func (r *REPL) loadGeneration() {
+	g, err := config.LoadGeneration()
+	if err != nil {
+		fmt.Printf("%sWarning: %s; using default generation parameters%s\n", colorYellow, err, colorReset)
+	}
+	provider.SetGeneration(g)
+}
+
+// showParams reloads generation.toml and shows what the active model is
+// asked with.
+func (r *REPL) showParams() {
+	r.loadGeneration()
+	p := provider.Params(r.client.ProviderName(), r.client.PrimaryModel())
+
+	fmt.Printf("Generation parameters for %s%s %s%s:\n", colorYellow, r.client.PrimaryName(), r.client.PrimaryModel(), colorReset)
+	fmt.Printf("  max_tokens       %d\n", p.MaxTokens)
+	if p.Temperature != nil {
+		fmt.Printf("  temperature      %g\n", *p.Temperature)
+	}
+	if p.TopP != nil {
+		fmt.Printf("  top_p            %g\n", *p.TopP)
+	}
+	if len(p.Stop) > 0 {
+		fmt.Printf("  stop             %q\n", p.Stop)
+	}
+	if p.Seed != nil {
+		fmt.Printf("  seed             %d\n", *p.Seed)
+	}
+	if p.ThinkingBudget != nil {
+		fmt.Printf("  thinking_budget  %d\n", *p.ThinkingBudget)
+	}
+	if p.ReasoningEffort != "" {
+		fmt.Printf("  reasoning_effort %s\n", p.ReasoningEffort)
+	}
+	if provider.Thinks(r.client.ProviderName(), r.client.PrimaryModel()) {
+		fmt.Println("  (a reasoning model: it thinks before answering)")
+	}
+	fmt.Printf("Set them in %s, for all providers or as %s.<key> and \"%s/<model>\".<key>\n",
+		config.GenerationPath(), r.client.ProviderName(), r.client.ProviderName())
+}
@@ -0,0 +1,176 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/markymn/nlcli/internal/shell"
+)
+
+const blastListLimit = 10
+
+func (r *REPL) showThreats(threats []shell.Threat) {
+	for _, t := range threats {
+		fmt.Printf("  %s%sWarning [%s]: %s%s\n", colorBold, colorRed, t.Kind, t.Reason, colorReset)
+	}
+}
+
+// flagElevation marks audit records of commands that run as another user.
+const flagElevation = "elevation"
+
+func (r *REPL) showElevation(mechanisms []string) {
+	if len(mechanisms) > 0 {
+		fmt.Printf("  %s%sWarning [%s]: runs with elevated privileges via %s%s\n",
+			colorBold, colorRed, flagElevation, strings.Join(mechanisms, ", "), colorReset)
+	}
+}
+
+func (r *REPL) showSecrets(names []string) {
+	if len(names) > 0 {
+		fmt.Printf("  %sUses secrets: %s%s\n", colorYellow, strings.Join(names, ", "), colorReset)
+	}
+}
+
+func threatKinds(threats []shell.Threat) []string {
+	var kinds []string
+	seen := make(map[string]bool)
+	for _, t := range threats {
+		if !seen[t.Kind] {
+			seen[t.Kind] = true
+			kinds = append(kinds, t.Kind)
+		}
+	}
+	return kinds
+}
+
+func (r *REPL) showBlastRadius(cmd string) {
+	br := shell.ComputeBlastRadius(cmd)
+	if br == nil || len(br.Targets) == 0 {
+		return
+	}
+
+	summary := fmt.Sprintf("%d files, %d dirs, %s", br.Files, br.Dirs, formatSize(br.TotalSize))
+	if br.Truncated {
+		summary = "at least " + summary
+	}
+	fmt.Printf("  %sAffects %d path(s): %s%s\n", colorCyan, len(br.Targets), summary, colorReset)
+
+	for _, t := range br.Largest(blastListLimit) {
+		switch {
+		case t.Missing:
+			fmt.Printf("    %s (not found)\n", t.Path)
+		case t.Files+t.Dirs > 1:
+			fmt.Printf("    %s (%d files, %d dirs, %s)\n", t.Path, t.Files, t.Dirs, formatSize(t.Size))
+		default:
+			fmt.Printf("    %s (%s)\n", t.Path, formatSize(t.Size))
+		}
+	}
+	if len(br.Targets) > blastListLimit {
+		fmt.Printf("    ... and %d more\n", len(br.Targets)-blastListLimit)
+	}
+}
+
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// confirm asks before running cmd, offering its dry run when it has one. The
+// dry run executes with run, the form with redacted secrets restored.
+func (r *REPL) confirm(cmd, run string) bool {
+	dryRun, caveat, hasDryRun := shell.DryRunCommand(r.shellType, cmd)
+	restored, _, ok := shell.DryRunCommand(r.shellType, run)
+	hasDryRun = hasDryRun && ok
+
+	for {
+		if hasDryRun {
+			fmt.Printf("%sExecute this command? [Enter to run / p to preview / Ctrl+C to cancel]%s ", colorCyan, colorReset)
+		} else {
+			fmt.Printf("%sExecute this command? [Enter to run / Ctrl+C to cancel]%s ", colorCyan, colorReset)
+		}
+
+		input, err := r.reader.ReadString('\n')
+		if err != nil {
+			fmt.Println()
+			return false
+		}
+
+		if hasDryRun && strings.EqualFold(strings.TrimSpace(input), "p") {
+			fmt.Printf("  %sPreview: %s%s\n", colorCyan, dryRun, colorReset)
+			if caveat != "" {
+				fmt.Printf("  %sThe preview is not free of side effects: %s.%s\n", colorYellow, caveat, colorReset)
+				fmt.Print("  Run the preview anyway? (y/N): ")
+				answer, _ := r.reader.ReadString('\n')
+				if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+					continue
+				}
+			}
+			if err := r.executor.ExecuteInteractive(restored); err != nil {
+				fmt.Printf("%sPreview failed: %s%s\n", colorRed, err, colorReset)
+			}
+			continue
+		}
+		return true
+	}
+}
+
+// previewInPlace runs an in-place editing command against temporary copies of
+// its target files and prints the resulting diff. It reports whether a
+// preview was shown, in which case the caller must confirm the real run.
+func (r *REPL) previewInPlace(cmd string) bool {
+	p, err := shell.PrepareInPlacePreview(r.shellType, cmd)
+	if err != nil {
+		fmt.Printf("%sPreview failed: %s%s\n", colorRed, err, colorReset)
+		return false
+	}
+	if p == nil {
+		return false
+	}
+	defer p.Cleanup()
+
+	if _, stderr, err := r.executor.Execute(p.Command); err != nil {
+		fmt.Printf("%sPreview failed: %s%s\n", colorRed, strings.TrimSpace(stderr+" "+err.Error()), colorReset)
+		return true
+	}
+
+	changed := false
+	for _, f := range p.Files {
+		before, err1 := os.ReadFile(f.Path)
+		after, err2 := os.ReadFile(f.Copy)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		diff := shell.UnifiedDiff(f.Path, f.Path+" (after)", string(before), string(after))
+		if diff == "" {
+			continue
+		}
+		changed = true
+		for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+			switch {
+			case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+				fmt.Printf("  %s%s%s\n", colorBold, line, colorReset)
+			case strings.HasPrefix(line, "@@"):
+				fmt.Printf("  %s%s%s\n", colorCyan, line, colorReset)
+			case strings.HasPrefix(line, "+"):
+				fmt.Printf("  %s%s%s\n", colorGreen, line, colorReset)
+			case strings.HasPrefix(line, "-"):
+				fmt.Printf("  %s%s%s\n", colorRed, line, colorReset)
+			default:
+				fmt.Printf("  %s\n", line)
+			}
+		}
+	}
+	if !changed {
+		fmt.Printf("  %sPreview: no changes to %d file(s)%s\n", colorCyan, len(p.Files), colorReset)
+	}
+	return true
+}
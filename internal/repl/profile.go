@@ -0,0 +1,137 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/markymn/nlcli/internal/config"
+	"github.com/markymn/nlcli/internal/provider"
+	"github.com/markymn/nlcli/internal/shell"
+)
+
+type settings struct {
+	safety          shell.SafetyLevel
+	direct          shell.SafetyLevel
+	trashMode       bool
+	forbidElevation bool
+}
+
+// loadProfile picks up the .nlcli.toml governing the current directory,
+// announcing when it changes.
+func (r *REPL) loadProfile() {
+	cwd, _ := os.Getwd()
+	p, err := config.FindProfile(cwd)
+	if err != nil {
+		if err.Error() != r.profileErr {
+			fmt.Printf("%sIgnoring profile: %s%s\n", colorRed, err, colorReset)
+		}
+		r.profileErr = err.Error()
+		p = nil
+	} else {
+		r.profileErr = ""
+	}
+
+	prevPath, path := "", ""
+	if r.profile != nil {
+		prevPath = r.profile.Path
+	}
+	if p != nil {
+		path = p.Path
+	}
+
+	r.profile = p
+	loosened := r.applyProfile()
+	switch {
+	case path != "" && path != prevPath:
+		fmt.Printf("Using profile %s%s%s (safety %s)\n", colorYellow, path, colorReset, r.safety)
+		if loosened {
+			fmt.Printf("%sThe profile would loosen your settings; only its stricter ones apply until you .trust it.%s\n",
+				colorYellow, colorReset)
+		}
+	case path == "" && prevPath != "":
+		fmt.Printf("Left profile %s, safety back to %s\n", prevPath, r.safety)
+	}
+}
+
+// applyProfile puts the profile's settings over the global ones. An
+// untrusted profile only gets to tighten them; applyProfile reports whether
+// it wanted to loosen any.
+func (r *REPL) applyProfile() (loosened bool) {
+	r.settings = r.global
+	if p := r.profile; p != nil {
+		want := r.global
+		if p.Safety != 0 {
+			want.safety = p.Safety
+		}
+		if p.Direct != nil {
+			want.direct = *p.Direct
+		}
+		if p.Trash != nil {
+			want.trashMode = *p.Trash
+		}
+		if p.ForbidElevation != nil {
+			want.forbidElevation = *p.ForbidElevation
+		}
+		if p.Trusted {
+			r.settings = want
+		} else {
+			r.safety = max(r.global.safety, want.safety)
+			r.direct = stricterDirect(r.global.direct, want.direct)
+			r.trashMode = r.global.trashMode || want.trashMode
+			r.forbidElevation = r.global.forbidElevation || want.forbidElevation
+			loosened = r.settings != want
+		}
+	}
+	// neither the user config nor a profile may go below the admin policy
+	if pol := r.policy; pol != nil {
+		r.safety = max(r.safety, pol.MinSafety)
+		r.forbidElevation = r.forbidElevation || pol.ForbidElevation
+	}
+	provider.SetForbidElevation(r.forbidElevation)
+	return loosened
+}
+
+// stricterDirect returns the direct level that checks more typed commands.
+// Checks apply from the level up, so lower levels are stricter, and 0 is off.
+func stricterDirect(a, b shell.SafetyLevel) shell.SafetyLevel {
+	switch {
+	case a == 0:
+		return b
+	case b == 0:
+		return a
+	}
+	return min(a, b)
+}
+
+// trustProfile lets the current directory's profile loosen settings too.
+func (r *REPL) trustProfile() {
+	if r.profile == nil {
+		fmt.Println("No .nlcli.toml applies here.")
+		return
+	}
+	if err := config.TrustProfile(r.profile); err != nil {
+		fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+		return
+	}
+	r.applyProfile()
+	fmt.Printf("Trusted %s%s%s (safety %s). Editing it revokes the trust.\n", colorYellow, r.profile.Path, colorReset, r.safety)
+}
+
+// noteOverride warns that a global setting just changed is shadowed by the
+// profile of the current directory.
+func (r *REPL) noteOverride(overridden bool) {
+	if overridden {
+		fmt.Printf("Note: %s overrides this setting here; the change applies elsewhere.\n", r.profile.Path)
+		r.applyProfile()
+	}
+}
+
+// denied reports (and explains) whether cmd matches a deny rule of the admin
+// policy. Deny rules apply to every command, including ones run with !.
+func (r *REPL) denied(cmd string) bool {
+	rule, ok := r.policy.Denied(cmd)
+	if ok {
+		fmt.Printf("%sBlocked: matches deny rule %q in %s%s\n", colorRed, rule, r.policy.Path, colorReset)
+	}
+	return ok
+}
@@ -0,0 +1,76 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/markymn/nlcli/internal/config"
+)
+
+// thinking shows an indicator with the time spent waiting until the returned
+// func is called.
+func thinking() (stop func()) {
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		start := time.Now()
+		for i := 0; ; i++ {
+			fmt.Printf("\r  %c thinking %ds", `|/-\`[i%4], int(time.Since(start).Seconds()))
+			select {
+			case <-done:
+				fmt.Print("\r\033[K")
+				return
+			case <-time.After(150 * time.Millisecond):
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// showReasoning prints the model's reasoning in explain mode.
+func (r *REPL) showReasoning() {
+	if !r.explain {
+		return
+	}
+	reasoning := r.client.Reasoning()
+	if reasoning == "" {
+		if r.client.Thinks() {
+			fmt.Println("  (the model did not return its reasoning)")
+		}
+		return
+	}
+	fmt.Printf("%sReasoning:%s\n", colorCyan, colorReset)
+	for _, line := range strings.Split(reasoning, "\n") {
+		fmt.Printf("  %s\n", line)
+	}
+}
+
+func (r *REPL) changeExplain(args []string) {
+	if len(args) == 0 {
+		state := "off"
+		if r.explain {
+			state = "on"
+		}
+		fmt.Printf("Show the model's reasoning: %s%s%s\n", colorYellow, state, colorReset)
+		fmt.Println("Usage: .explain <on|off>")
+		return
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "on":
+		r.explain = true
+	case "off":
+		r.explain = false
+	default:
+		fmt.Printf("%sUsage: .explain <on|off>%s\n", colorRed, colorReset)
+		return
+	}
+	if err := config.SaveExplain(r.explain); err != nil {
+		fmt.Printf("%sError saving config: %s%s\n", colorRed, err, colorReset)
+	}
+	r.changeExplain(nil)
+}
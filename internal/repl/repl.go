@@ -8,11 +8,15 @@ import (
 	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/markymn/nlcli/internal/audit"
 	"github.com/markymn/nlcli/internal/config"
 	"github.com/markymn/nlcli/internal/history"
 	"github.com/markymn/nlcli/internal/provider"
 	"github.com/markymn/nlcli/internal/shell"
+	"github.com/markymn/nlcli/internal/usage"
+	"github.com/markymn/nlcli/internal/vault"
 )
 
 const (
@@ -21,28 +25,85 @@ const (
 	colorYellow = "\033[33m"
 	colorCyan   = "\033[36m"
 	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
 	colorBold   = "\033[1m"
 )
 
+// Typing this before a command runs it as is, skipping the direct command
+// safety check. Detected threats are still confirmed. It is a dot command so
+// shell negation and history like ! grep or !! keep their meaning.
+const bypassPrefix = ".run "
+
 type REPL struct {
 	client    *provider.MultiClient
 	executor  *shell.Executor
 	shellType shell.ShellType
 	history   *history.History
 	reader    *bufio.Reader
-	safety    shell.SafetyLevel
+	auditLog  bool
+	explain   bool
+
+	// settings are the ones in effect in the current directory: global
+	// overlaid with the nearest .nlcli.toml profile.
+	settings
+	global     settings
+	profile    *config.Profile
+	profileErr string
+	policy     *config.Policy
+	vault      *vault.Vault
+	ledger     *usage.Ledger
+	budget     *usage.Budget
 }
 
 func New(client *provider.MultiClient, executor *shell.Executor, shellType shell.ShellType) *REPL {
 	os.Setenv("NLCLI_INSIDE", "1")
-	return &REPL{
+
+	// a policy that exists but cannot be read must not mean no policy
+	policy, err := config.LoadPolicy()
+	if err != nil {
+		fmt.Printf("%sError: admin policy: %s%s\n", colorRed, err, colorReset)
+		os.Exit(1)
+	}
+	provider.SetPolicy(policy)
+
+	r := &REPL{
 		client:    client,
 		executor:  executor,
 		shellType: shellType,
 		history:   history.New(),
 		reader:    bufio.NewReader(os.Stdin),
-		safety:    shell.SafetyLevel(config.LoadSafetyLevel()),
+		auditLog:  config.LoadAuditLog(),
+		explain:   config.LoadExplain(),
+		global: settings{
+			safety:          shell.SafetyLevel(config.LoadSafetyLevel()),
+			trashMode:       config.LoadTrashMode(),
+			direct:          shell.SafetyLevel(config.LoadDirectPolicy()),
+			forbidElevation: config.LoadForbidElevation(),
+		},
+		policy: policy,
+	}
+	if policy != nil && policy.RequireAudit {
+		r.auditLog = true
 	}
+	// before anything below reads an API key from the vault
+	config.SetVaultPrompt(r.readSecret)
+	// keys saved before per-provider storage belong to the client's provider
+	if name, _ := config.LoadProvider(); name == "" && client.ProviderName() != "" {
+		config.SaveProvider(client.ProviderName())
+	}
+	prices, err := config.LoadPrices()
+	if err != nil {
+		fmt.Printf("%sWarning: %s; using default prices%s\n", colorYellow, err, colorReset)
+	}
+	r.ledger = usage.NewLedger(prices)
+	r.loadGeneration()
+	r.configureClient()
+	r.loadBudget()
+	r.executor.SetSecretResolver(r.resolveSecret)
+	names, _ := vault.Names()
+	provider.SetSecretNames(names)
+	r.applyProfile()
+	return r
 }
 
 func (r *REPL) Start() {
@@ -51,9 +112,13 @@ func (r *REPL) Start() {
 	fmt.Printf("Shell:    %s%s%s\n", colorYellow, shell.GetShellName(r.shellType), colorReset)
 	fmt.Printf("Provider: %s%s%s\n", colorYellow, r.client.PrimaryName(), colorReset)
 	fmt.Printf("Model:    %s%s%s\n", colorYellow, r.client.PrimaryModel(), colorReset)
+	if config.HasPlaintextAPIKey() {
+		fmt.Printf("%sYour API key is stored in plaintext; run .vault migrate to encrypt it.%s\n", colorYellow, colorReset)
+	}
 	fmt.Println()
 
 	for {
+		r.loadProfile()
 		r.printPrompt()
 
 		input, err := r.reader.ReadString('\n')
@@ -66,20 +131,30 @@ func (r *REPL) Start() {
 			continue
 		}
 
+		if strings.HasPrefix(input, bypassPrefix) {
+			r.runDirect(strings.TrimSpace(strings.TrimPrefix(input, bypassPrefix)), true)
+			continue
+		}
+
 		if r.handleSpecial(input) {
+			r.audit(audit.Record{Kind: "special", Input: input})
 			continue
 		}
 
 		if strings.HasPrefix(input, "cd ") || input == "cd" {
 			path := strings.TrimPrefix(input, "cd")
-			if err := shell.ExecuteCD(path); err != nil {
+			err := shell.ExecuteCD(path)
+			if err != nil {
 				fmt.Printf("%s%s%s\n", colorRed, err, colorReset)
 			}
+			rec := audit.Record{Kind: "cd", Input: input, Command: input, Risk: shell.RiskNone.String()}
+			rec.SetExitCode(shell.ExitCode(err))
+			r.audit(rec)
 			continue
 		}
 
 		if shell.IsValidSyntax(r.shellType, input) {
-			r.runCommand(input)
+			r.runDirect(input, false)
 			continue
 		}
 
@@ -104,7 +179,10 @@ func (r *REPL) setupSignals() {
 }
 
 func (r *REPL) handleSpecial(input string) bool {
-	switch strings.ToLower(input) {
+	fields := strings.Fields(input)
+	args := fields[1:]
+
+	switch strings.ToLower(fields[0]) {
 	case ".exit":
 		os.Exit(0)
 	case ".help":
@@ -116,12 +194,66 @@ func (r *REPL) handleSpecial(input string) bool {
 	case ".model":
 		r.changeModel()
 		return true
+	case ".use":
+		r.handleUse(args)
+		return true
+	case ".fallback":
+		r.changeFallback(args)
+		return true
+	case ".strategy":
+		r.changeStrategy(args)
+		return true
+	case ".usage":
+		r.showUsage(args)
+		return true
+	case ".params":
+		r.showParams()
+		return true
+	case ".budget":
+		r.showBudget()
+		return true
+	case ".compare":
+		r.compare(strings.TrimSpace(input[len(fields[0]):]))
+		return true
 	case ".uninstall":
 		r.uninstall()
 		return true
 	case ".safety":
 		r.changeSafety()
 		return true
+	case ".trash":
+		r.handleTrash(args)
+		return true
+	case ".undo":
+		r.undoTrash()
+		return true
+	case ".restore":
+		r.restoreSnapshot(args)
+		return true
+	case ".audit":
+		r.handleAudit(args)
+		return true
+	case ".direct":
+		r.changeDirectPolicy(args)
+		return true
+	case ".elevation":
+		r.changeElevation(args)
+		return true
+	case ".trust":
+		r.trustProfile()
+		return true
+	case ".verify":
+		r.changeVerifier(args)
+		return true
+	case ".explain":
+		r.changeExplain(args)
+		return true
+	case ".secret":
+		r.handleSecret(args)
+		return true
+	case ".vault":
+		r.handleVault(args)
+		return true
 	}
 	return false
 }
@@ -131,81 +263,157 @@ func (r *REPL) showHelp() {
 	fmt.Printf("Shell:    %s%s%s\n", colorYellow, shell.GetShellName(r.shellType), colorReset)
 	fmt.Printf("Provider: %s%s%s\n", colorYellow, r.client.PrimaryName(), colorReset)
 	fmt.Printf("Model:    %s%s%s\n", colorYellow, r.client.PrimaryModel(), colorReset)
-	fmt.Printf("Safety:   %s%s%s\n\n", colorYellow, r.safety.String(), colorReset)
+	fmt.Printf("Safety:   %s%s%s\n", colorYellow, r.safety.String(), colorReset)
+	fmt.Printf("Direct:   %s%s%s\n", colorYellow, directPolicyName(r.direct), colorReset)
+	if r.profile != nil {
+		fmt.Printf("Profile:  %s%s%s\n", colorYellow, r.profile.Path, colorReset)
+	}
+	if r.policy != nil {
+		fmt.Printf("Policy:   %s%s%s\n", colorYellow, r.policy.Path, colorReset)
+	}
+	fmt.Println()
 	fmt.Println("Usage:")
 	fmt.Println("  Type naturally   System translates to shell command")
 	fmt.Println("  Type command     Runs directly (syntax validated)")
 	fmt.Println("  cd <path>        Change directory")
+	fmt.Println("  .run <command>   Run command directly, skipping confirmation")
 	fmt.Println()
 	fmt.Println("Special commands:")
 	fmt.Println("  .help            Show this help")
 	fmt.Println("  .api             Change API key and model")
 	fmt.Println("  .model           Change model only")
+	fmt.Println("  .use <p>[/<m>]   Switch to a provider (and model) whose key is stored")
+	fmt.Println("  .fallback ...    Providers to try in order when the active one fails (or off)")
+	fmt.Println("  .strategy <s>    Use the chain as fallback, race or quorum")
+	fmt.Println("  .compare <text>  Ask every configured model and pick a command to run")
+	fmt.Println("  .usage [days]    Show tokens and cost this session, today and this month")
+	fmt.Println("  .budget          Show spending limits from budget.toml and what is left")
+	fmt.Println("  .params          Show temperature, max tokens and other generation parameters")
 	fmt.Println("  .safety          Change safety level (Instant, Lax, Cautious, Strict)")
+	fmt.Println("  .direct <level>  Check typed commands from this level up (or off)")
+	fmt.Println("  .elevation       Allow or forbid sudo, doas, su in translated commands")
+	fmt.Println("  .trust           Let this directory's .nlcli.toml loosen settings too")
+	fmt.Println("  .verify <model>  Have a second model review commands (or off)")
+	fmt.Println("  .explain on|off  Show the reasoning of thinking models with each command")
+	fmt.Println("  .secret          List, set or rm secrets usable as {{secret:NAME}}")
+	fmt.Println("  .vault           Encrypt the API key (migrate, keyfile, lock)")
+	fmt.Println("  .trash [on|off]  List trashed deletions or toggle trash mode")
+	fmt.Println("  .trash purge     Permanently delete trash (all, or one id)")
+	fmt.Println("  .undo            Restore the last trashed deletion")
+	fmt.Println("  .restore [id]    List snapshots or restore one")
+	fmt.Println("  .restore purge   Delete snapshots (all, or one id)")
+	fmt.Println("  .audit verify    Check the audit log hash chain")
 	fmt.Println("  .uninstall       Remove nlcli")
 	fmt.Println("  .exit            Exit nlcli")
 	fmt.Println()
 }
 
 func (r *REPL) changeAPI() {
-	var key string
-	var providerName string
-	var err error
-
-	for {
-		key, err = config.SetupAPIKey()
-		if err != nil {
-			fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
-			return
-		}
-
-		providerName, _ = provider.DetectProvider(key)
-		if providerName != "" {
-			break
-		}
-
-		fmt.Printf("%sInvalid API key format.%s\n", colorRed, colorReset)
-		fmt.Println("Supported providers:")
-		fmt.Println("  OpenAI")
-		fmt.Println("  Anthropic")
-		fmt.Println("  Google")
-		fmt.Println("  Groq")
-		fmt.Println()
+	key, err := config.SetupAPIKey()
+	if err != nil {
+		fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+		return
 	}
 
+	providerName, err := pickProvider(key)
+	if err != nil {
+		fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+		return
+	}
 	displayName := provider.GetProviderDisplayName(providerName)
 
-	fmt.Printf("\033[32mDetected provider: %s\033[0m\n", displayName)
-	fmt.Printf("Fetching available models...\n")
-	models, fetchErr := provider.FetchModels(providerName, key)
-	if fetchErr != nil || len(models) == 0 {
-		models = provider.GetModels(providerName)
+	// the key only ever goes to the provider the user picked
+	models := provider.GetModels(providerName)
+	fmt.Printf("Validate the key with %s now? (Y/n): ", displayName)
+	answer, _ := r.reader.ReadString('\n')
+	if a := strings.ToLower(strings.TrimSpace(answer)); a == "" || a == "y" || a == "yes" {
+		fetched, err := provider.FetchModels(providerName, key)
+		switch {
+		case err != nil:
+			fmt.Printf("%sWarning: %s did not accept the key: %s%s\n", colorYellow, displayName, err, colorReset)
+		case len(fetched) > 0:
+			fmt.Printf("%sKey accepted by %s%s\n", colorGreen, displayName, colorReset)
+			models = fetched
+		}
 	}
 	model, err := config.SelectModel(models, displayName)
 	if err != nil {
 		model = models[0]
 	}
 
-	if err := config.SaveConfig(key, model, int(r.safety)); err != nil {
+	if err := config.SaveProviderKey(providerName, key); err != nil {
+		fmt.Printf("%sError saving config: %s%s\n", colorRed, err, colorReset)
+		return
+	}
+	if err := config.SaveProviderModel(providerName, model); err != nil {
+		fmt.Printf("%sError saving config: %s%s\n", colorRed, err, colorReset)
+		return
+	}
+	if err := config.SaveProvider(providerName); err != nil {
 		fmt.Printf("%sError saving config: %s%s\n", colorRed, err, colorReset)
 		return
 	}
+	r.switchClient(key, model, providerName)
+}
+
+// pickProvider lets the user choose the provider for key, starting on the
+// one its prefix suggests. Providers the admin policy forbids are not offered.
+func pickProvider(key string) (string, error) {
+	detected, _ := provider.DetectProvider(key)
+
+	var names, options []string
+	def := 0
+	for _, name := range provider.Names {
+		if !provider.NeedsKey(name) || provider.Allowed(name) != nil {
+			continue
+		}
+		if name == detected {
+			def = len(options)
+		}
+		names = append(names, name)
+		options = append(options, provider.GetProviderDisplayName(name))
+	}
+	if len(options) == 0 {
+		return "", fmt.Errorf("the admin policy allows no providers")
+	}
 
-	r.client = provider.NewMultiClient(key, model, providerName, nil)
-	fmt.Printf("Switched to %s (%s)\n", r.client.PrimaryName(), r.client.PrimaryModel())
+	title := "Provider"
+	if detected != "" {
+		title += " (key looks like " + provider.GetProviderDisplayName(detected) + ")"
+	}
+	selected, err := config.SelectWithDefault(options, title, def)
+	if err != nil {
+		return "", err
+	}
+	for i, option := range options {
+		if option == selected {
+			return names[i], nil
+		}
+	}
+	return "", fmt.Errorf("no provider selected")
 }
 
 func (r *REPL) changeModel() {
-	key, err := config.LoadAPIKey()
-	if err != nil || key == "" {
-		fmt.Printf("%sError: No API key found. Please use .api first.%s\n", colorRed, colorReset)
+	providerName, _ := config.LoadProvider()
+	var key string
+	if providerName == "" || provider.NeedsKey(providerName) {
+		var err error
+		key, err = config.LoadAPIKey()
+		if err != nil || key == "" {
+			fmt.Printf("%sError: No API key found. Please use .api first.%s\n", colorRed, colorReset)
+			return
+		}
+		if providerName == "" {
+			providerName, _ = provider.DetectProvider(key)
+		}
+	}
+	if err := provider.Allowed(providerName); err != nil {
+		fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
 		return
 	}
-
-	providerName, _ := provider.DetectProvider(key)
 	displayName := provider.GetProviderDisplayName(providerName)
 
-	fmt.Printf("\033[32mDetected provider: %s\033[0m\n", displayName)
+	fmt.Printf("\033[32mProvider: %s\033[0m\n", displayName)
 	fmt.Printf("Fetching available models...\n")
 	models, fetchErr := provider.FetchModels(providerName, key)
 	if fetchErr != nil || len(models) == 0 {
@@ -217,13 +425,11 @@ func (r *REPL) changeModel() {
 		return
 	}
 
-	if err := config.SaveConfig(key, model, int(r.safety)); err != nil {
+	if err := config.SaveProviderModel(providerName, model); err != nil {
 		fmt.Printf("%sError saving config: %s%s\n", colorRed, err, colorReset)
 		return
 	}
-
-	r.client = provider.NewMultiClient(key, model, providerName, nil)
-	fmt.Printf("Switched to %s (%s)\n", r.client.PrimaryName(), r.client.PrimaryModel())
+	r.switchClient(key, model, providerName)
 }
 
 func (r *REPL) uninstall() {
@@ -244,16 +450,19 @@ func (r *REPL) uninstall() {
 	os.Exit(0)
 }
 
-func (r *REPL) runCommand(cmd string) {
+// runCommand executes cmd and adds logged to the history. They differ when cmd
+// has secrets restored that the history must not keep.
+func (r *REPL) runCommand(cmd, logged string) int {
 	cmd = strings.TrimSpace(cmd)
 	if strings.HasPrefix(cmd, "cd ") || cmd == "cd" {
 		path := strings.TrimPrefix(cmd, "cd")
 		path = strings.TrimSpace(path)
-		if err := shell.ExecuteCD(path); err != nil {
+		err := shell.ExecuteCD(path)
+		if err != nil {
 			fmt.Printf("%s%s%s\n", colorRed, err, colorReset)
 		}
-		r.history.Add(cmd, "")
-		return
+		r.history.Add(logged, "")
+		return shell.ExitCode(err)
 	}
 
 	err := r.executor.ExecuteInteractive(cmd)
@@ -261,18 +470,95 @@ func (r *REPL) runCommand(cmd string) {
 	if err != nil {
 		output = err.Error()
 	}
-	r.history.Add(cmd, output)
+	r.history.Add(logged, output)
+	return shell.ExitCode(err)
+}
+
+func (r *REPL) runDirect(input string, bypass bool) {
+	rec := audit.Record{Kind: "direct", Input: input, Command: input, Risk: shell.AssessRisk(input).String(),
+		Confirmation: audit.ConfirmNotRequired}
+
+	if r.denied(input) {
+		rec.Error = "denied by policy"
+		r.audit(rec)
+		return
+	}
+
+	threats := shell.DetectThreats(input)
+	rec.Flags = threatKinds(threats)
+	if len(shell.DetectElevation(input)) > 0 {
+		rec.Flags = append(rec.Flags, flagElevation)
+	}
+
+	// the bypass skips the safety level check, not the threat warnings
+	switch {
+	case bypass && len(threats) == 0:
+		rec.Confirmation = audit.ConfirmBypassed
+	case len(threats) > 0 || !bypass && r.direct != 0 && r.safety >= r.direct && shell.IsDangerous(input, r.safety):
+		r.showThreats(threats)
+		r.showBlastRadius(input)
+		if !r.confirm(input, input) {
+			rec.Confirmation = audit.ConfirmDeclined
+			r.audit(rec)
+			return
+		}
+		rec.Confirmation = audit.ConfirmAccepted
+	}
+
+	start := time.Now()
+	rec.SetExitCode(r.runCommand(input, input))
+	rec.DurationMS = time.Since(start).Milliseconds()
+	r.audit(rec)
 }
 
 func (r *REPL) translateAndRun(input string) {
 	cwd, _ := os.Getwd()
 
+	rec := audit.Record{Kind: "translate", Input: input, Provider: r.client.PrimaryName(), Model: r.client.PrimaryModel(), Cwd: cwd}
+	defer func() { r.audit(rec) }()
+	defer r.checkLedger()
+
+	stop := func() {}
+	if r.client.Thinks() {
+		stop = thinking()
+	}
 	cmd, err := r.client.GetCommand(input, cwd, r.shellType, r.history)
+	stop()
 	if err != nil {
 		fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+		rec.Error = err.Error()
 		return
 	}
+	if p, _ := r.client.Answered(); p != nil {
+		rec.Provider, rec.Model = p.Name(), p.Model()
+	}
+	r.showAnswered()
+	if r.showBudgetNote() {
+		rec.Flags = append(rec.Flags, "over-budget")
+	}
+	if r.client.Retried() {
+		fmt.Println("  (the first reply was cut off; asked again with a larger token limit)")
+		rec.Flags = append(rec.Flags, "retried")
+	}
+	if n := r.client.Redacted(); n > 0 {
+		fmt.Printf("  (%d secret(s) replaced with placeholders before sending)\n", n)
+		rec.Flags = append(rec.Flags, "redacted")
+	}
+
+	r.showReasoning()
+
+	cmd = cleanCommand(cmd)
+	if cmd == "" {
+		fmt.Printf("%sError: Could not translate to a command.%s\n", colorRed, colorReset)
+		rec.Error = "could not translate to a command"
+		return
+	}
+	r.runTranslated(input, cmd, &rec)
+}
 
+// cleanCommand strips the markdown fences and shell labels some models add
+// around the command.
+func cleanCommand(cmd string) string {
 	cmd = strings.TrimSpace(cmd)
 
 	// Strip markdown blocks if present
@@ -294,25 +580,87 @@ func (r *REPL) translateAndRun(input string) {
 			break
 		}
 	}
-	cmd = strings.TrimSpace(cmd)
+	return strings.TrimSpace(cmd)
+}
 
-	if cmd == "" {
-		fmt.Printf("%sError: Could not translate to a command.%s\n", colorRed, colorReset)
+// runTranslated shows a command returned by a model and runs it after the
+// policy, threat, elevation and verifier checks, recording the outcome in rec.
+// cmd keeps the placeholders of redacted secrets for display, the history and
+// the audit log; only the paths it touches and what runs get them restored.
+func (r *REPL) runTranslated(input, cmd string, rec *audit.Record) {
+	fmt.Printf("  %s%s%s\n", colorYellow, cmd, colorReset)
+	run := r.client.Restore(cmd)
+
+	rec.Command = cmd
+	if r.denied(cmd) {
+		rec.Error = "denied by policy"
 		return
 	}
 
-	fmt.Printf("  %s%s%s\n", colorYellow, cmd, colorReset)
+	risk := shell.AssessRisk(cmd)
+	threats := shell.DetectThreats(cmd)
+	rec.Risk = risk.String()
+	rec.Flags = append(rec.Flags, threatKinds(threats)...)
+	rec.Confirmation = audit.ConfirmNotRequired
+
+	elevation := shell.DetectElevation(cmd)
+	if len(elevation) > 0 {
+		rec.Flags = append(rec.Flags, flagElevation)
+		if r.forbidElevation {
+			fmt.Printf("%sRefusing to run: the command uses %s and elevation is forbidden (see .elevation).%s\n",
+				colorRed, strings.Join(elevation, ", "), colorReset)
+			rec.Error = "elevation forbidden"
+			return
+		}
+	}
 
-	if shell.IsDangerous(cmd, r.safety) {
-		fmt.Printf("%sExecute this command? [Enter to run / Ctrl+C to cancel]%s ", colorCyan, colorReset)
-		_, err := r.reader.ReadString('\n')
-		if err != nil {
-			fmt.Println()
+	objection := r.review(input, cmd, risk)
+	if objection != "" {
+		rec.Flags = append(rec.Flags, flagObjection)
+	}
+
+	secrets := shell.SecretRefs(cmd)
+
+	// the preview runs the command, so never one that was flagged
+	previewed := len(threats) == 0 && len(elevation) == 0 && objection == "" && r.previewInPlace(run)
+
+	if previewed || needsConfirmation(cmd, r.safety, threats, elevation, secrets, objection) {
+		r.showThreats(threats)
+		r.showElevation(elevation)
+		r.showSecrets(secrets)
+		r.showObjection(objection)
+		r.showBlastRadius(run)
+		if !r.confirm(cmd, run) {
+			rec.Confirmation = audit.ConfirmDeclined
 			return
 		}
+		rec.Confirmation = audit.ConfirmAccepted
 	}
 
-	r.runCommand(cmd)
+	start := time.Now()
+	defer func() { rec.DurationMS = time.Since(start).Milliseconds() }()
+
+	if r.trashMode {
+		if paths, ok := shell.SimpleRemoveTargets(run); ok {
+			rec.Kind = "trash"
+			rec.SetExitCode(r.moveToTrash(cmd, paths))
+			return
+		}
+	}
+
+	if risk == shell.RiskHigh && !r.takeSnapshot(cmd, run) {
+		rec.Confirmation = audit.ConfirmDeclined
+		return
+	}
+
+	rec.SetExitCode(r.runCommand(run, cmd))
+}
+
+// needsConfirmation reports whether a translated command must be confirmed
+// at the given safety level. Threats, elevation, secret references and
+// verifier objections always need confirmation, even at Instant.
+func needsConfirmation(cmd string, safety shell.SafetyLevel, threats []shell.Threat, elevation, secrets []string, objection string) bool {
+	return len(threats) > 0 || len(elevation) > 0 || len(secrets) > 0 || objection != "" || shell.IsDangerous(cmd, safety)
 }
 
 func (r *REPL) changeSafety() {
@@ -339,6 +687,77 @@ func (r *REPL) changeSafety() {
 		r.safety = shell.SafetyStrict
 	}
 
+	if r.policy != nil && r.safety < r.policy.MinSafety {
+		fmt.Printf("%sError: %s requires at least %s%s\n", colorRed, r.policy.Path, r.policy.MinSafety, colorReset)
+		r.applyProfile()
+		return
+	}
+
+	r.global.safety = r.safety
 	config.SaveSafetyLevel(int(r.safety))
 	fmt.Printf("Safety level set to: %s%s%s\n", colorYellow, r.safety.String(), colorReset)
+	r.noteOverride(r.profile != nil && r.profile.Safety != 0)
+}
+
+func (r *REPL) changeElevation(args []string) {
+	if len(args) == 0 {
+		state := "allowed"
+		if r.forbidElevation {
+			state = "forbidden"
+		}
+		fmt.Printf("Privilege escalation in translated commands: %s%s%s\n", colorYellow, state, colorReset)
+		fmt.Println("Usage: .elevation <allow|forbid>")
+		return
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "allow":
+		if r.policy != nil && r.policy.ForbidElevation {
+			fmt.Printf("%sError: elevation is forbidden by %s%s\n", colorRed, r.policy.Path, colorReset)
+			return
+		}
+		r.forbidElevation = false
+	case "forbid":
+		r.forbidElevation = true
+	default:
+		fmt.Printf("%sUsage: .elevation <allow|forbid>%s\n", colorRed, colorReset)
+		return
+	}
+	r.global.forbidElevation = r.forbidElevation
+	provider.SetForbidElevation(r.forbidElevation)
+	if err := config.SaveForbidElevation(r.forbidElevation); err != nil {
+		fmt.Printf("%sError saving config: %s%s\n", colorRed, err, colorReset)
+	}
+	r.changeElevation(nil)
+	r.noteOverride(r.profile != nil && r.profile.ForbidElevation != nil)
+}
+
+func (r *REPL) changeDirectPolicy(args []string) {
+	if len(args) == 0 {
+		fmt.Printf("Typed commands are checked: %s%s%s\n", colorYellow, directPolicyName(r.direct), colorReset)
+		fmt.Println("Usage: .direct <off|lax|cautious|strict>")
+		return
+	}
+
+	level, ok := shell.ParseSafetyLevel(args[0])
+	switch {
+	case strings.EqualFold(args[0], "off"):
+		level = 0
+	case !ok || level == shell.SafetyInstant:
+		fmt.Printf("%sUsage: .direct <off|lax|cautious|strict>%s\n", colorRed, colorReset)
+		return
+	}
+
+	r.direct = level
+	r.global.direct = level
+	config.SaveDirectPolicy(int(r.direct))
+	fmt.Printf("Typed commands are checked: %s%s%s\n", colorYellow, directPolicyName(r.direct), colorReset)
+	r.noteOverride(r.profile != nil && r.profile.Direct != nil)
+}
+
+func directPolicyName(level shell.SafetyLevel) string {
+	if level == 0 {
+		return "never"
+	}
+	return "at " + level.String() + " and above"
 }
@@ -0,0 +1,144 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/markymn/nlcli/internal/config"
+	"github.com/markymn/nlcli/internal/provider"
+	"github.com/markymn/nlcli/internal/vault"
+	"golang.org/x/term"
+)
+
+// readSecret reads a line without echoing it when stdin is a terminal.
+func (r *REPL) readSecret(prompt string) (string, error) {
+	fmt.Print(prompt)
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := r.reader.ReadString('\n')
+		return strings.TrimRight(line, "\r\n"), err
+	}
+	data, err := term.ReadPassword(fd)
+	fmt.Println()
+	return string(data), err
+}
+
+// unlockVault asks for the passphrase (or reads the key file) once per
+// session, creating the vault on first use.
+func (r *REPL) unlockVault() (*vault.Vault, error) {
+	return vault.Unlock(r.readSecret)
+}
+
+// resolveSecret is the executor's lookup for {{secret:NAME}}.
+func (r *REPL) resolveSecret(name string) (string, error) {
+	v, err := r.unlockVault()
+	if err != nil {
+		return "", err
+	}
+	value, ok := v.Get(name)
+	if !ok {
+		return "", fmt.Errorf("no secret %s in the vault (add it with .secret set %s)", name, name)
+	}
+	return value, nil
+}
+
+func (r *REPL) handleSecret(args []string) {
+	if len(args) == 0 || args[0] == "list" {
+		names, err := vault.Names()
+		if err != nil {
+			fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+			return
+		}
+		if len(names) == 0 {
+			fmt.Println("No secrets stored. Add one with .secret set NAME")
+			return
+		}
+		for _, name := range names {
+			fmt.Printf("  {{secret:%s}}\n", name)
+		}
+		return
+	}
+
+	if len(args) < 2 || (args[0] != "set" && args[0] != "rm") {
+		fmt.Printf("%sUsage: .secret [list] | .secret set NAME | .secret rm NAME%s\n", colorRed, colorReset)
+		return
+	}
+	name := args[1]
+	if !vault.ValidName(name) {
+		fmt.Printf("%sInvalid secret name %q (use letters, digits and _)%s\n", colorRed, name, colorReset)
+		return
+	}
+
+	v, err := r.unlockVault()
+	if err != nil {
+		fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+		return
+	}
+
+	switch args[0] {
+	case "set":
+		value, err := r.readSecret("Value for " + name + ": ")
+		if err != nil || value == "" {
+			fmt.Println("Cancelled.")
+			return
+		}
+		v.Set(name, value)
+	case "rm":
+		if !v.Delete(name) {
+			fmt.Printf("%sNo secret %s%s\n", colorRed, name, colorReset)
+			return
+		}
+	}
+
+	if err := v.Save(); err != nil {
+		fmt.Printf("%sError saving vault: %s%s\n", colorRed, err, colorReset)
+		return
+	}
+	provider.SetSecretNames(v.Names())
+	if args[0] == "set" {
+		fmt.Printf("Saved. Use it as {{secret:%s}}\n", name)
+	} else {
+		fmt.Printf("Removed %s\n", name)
+	}
+}
+
+func (r *REPL) handleVault(args []string) {
+	if len(args) == 0 {
+		fmt.Printf("API key storage: %s%s%s\n", colorYellow, config.CredentialStore(), colorReset)
+		fmt.Printf("Vault:           %s\n", vault.Path())
+		keyFile := vault.KeyFilePath()
+		if keyFile == "" {
+			keyFile = "none (set " + vault.KeyFileEnv + ")"
+		}
+		fmt.Printf("Key file:        %s\n", keyFile)
+		fmt.Println("Usage: .vault migrate | .vault keyfile | .vault lock")
+		return
+	}
+
+	switch args[0] {
+	case "migrate":
+		if err := config.MigrateToVault(); err != nil {
+			fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+			return
+		}
+		fmt.Println("API keys moved into the encrypted vault and removed from .env.")
+	case "keyfile":
+		v, err := r.unlockVault()
+		if err != nil {
+			fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+			return
+		}
+		path, err := vault.CreateKeyFile(v)
+		if err != nil {
+			fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+			return
+		}
+		fmt.Printf("Key file %s now unlocks the vault too; the passphrase still works.\n", path)
+	case "lock":
+		vault.Lock()
+		fmt.Println("Vault locked.")
+	default:
+		fmt.Printf("%sUsage: .vault migrate | .vault keyfile | .vault lock%s\n", colorRed, colorReset)
+	}
+}
@@ -0,0 +1,100 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/markymn/nlcli/internal/shell"
+	"github.com/markymn/nlcli/internal/snapshot"
+)
+
+// takeSnapshot reports whether run may go ahead. The snapshot covers the
+// paths run touches and records cmd, which keeps secrets as placeholders.
+// When the snapshot fails the user has to agree to run it without one.
+func (r *REPL) takeSnapshot(cmd, run string) bool {
+	paths := shell.AffectedPaths(run)
+	if len(paths) == 0 {
+		return true
+	}
+
+	snap, err := snapshot.Take(cmd, paths, shell.ChangesPermissions(run))
+	if err != nil {
+		fmt.Printf("%sWarning: no snapshot taken: %s%s\n", colorYellow, err, colorReset)
+		fmt.Print("Run it without a snapshot? (y/N): ")
+		input, _ := r.reader.ReadString('\n')
+		if strings.ToLower(strings.TrimSpace(input)) != "y" {
+			fmt.Println("Cancelled.")
+			return false
+		}
+		return true
+	}
+	fmt.Printf("  %sSnapshot %s saved (.restore %s to roll back)%s\n", colorCyan, snap.ID, snap.ID, colorReset)
+	return true
+}
+
+func (r *REPL) restoreSnapshot(args []string) {
+	if len(args) == 0 {
+		r.listSnapshots()
+		return
+	}
+	if strings.ToLower(args[0]) == "purge" {
+		r.purgeSnapshots(args[1:])
+		return
+	}
+
+	fmt.Printf("Restore snapshot %s over the current files? (y/N): ", args[0])
+	input, _ := r.reader.ReadString('\n')
+	if strings.ToLower(strings.TrimSpace(input)) != "y" {
+		fmt.Println("Cancelled.")
+		return
+	}
+
+	snap, err := snapshot.Restore(args[0])
+	if err != nil {
+		fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+		return
+	}
+	fmt.Printf("Restored %d path(s) from before: %s\n", len(snap.Paths), snap.Command)
+}
+
+func (r *REPL) listSnapshots() {
+	snapshots, err := snapshot.List()
+	if err != nil {
+		fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+		return
+	}
+	if len(snapshots) == 0 {
+		fmt.Println("No snapshots.")
+		return
+	}
+	for _, s := range snapshots {
+		kind := "tar"
+		if len(s.Git) > 0 && s.Archive == "" {
+			kind = "git"
+		} else if len(s.Git) > 0 {
+			kind = "git+tar"
+		}
+		fmt.Printf("  %s%-4s%s %s  %-7s %d path(s)  %s\n", colorYellow, s.ID, colorReset,
+			s.Time.Format("2006-01-02 15:04"), kind, len(s.Paths), s.Command)
+	}
+}
+
+func (r *REPL) purgeSnapshots(args []string) {
+	id := ""
+	if len(args) > 0 {
+		id = args[0]
+	} else {
+		fmt.Print("Permanently delete every snapshot? (y/N): ")
+		input, _ := r.reader.ReadString('\n')
+		if strings.ToLower(strings.TrimSpace(input)) != "y" {
+			fmt.Println("Cancelled.")
+			return
+		}
+	}
+	n, err := snapshot.Purge(id)
+	if err != nil {
+		fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+		return
+	}
+	fmt.Printf("Purged %d snapshot(s).\n", n)
+}
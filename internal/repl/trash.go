@@ -0,0 +1,99 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/markymn/nlcli/internal/config"
+	"github.com/markymn/nlcli/internal/trash"
+)
+
+func (r *REPL) moveToTrash(cmd string, paths []string) int {
+	entry, err := trash.Move(cmd, paths)
+	if entry != nil {
+		fmt.Printf("Moved %d item(s) to trash (%s). Use .undo to restore.\n", len(entry.Items), entry.ID)
+	}
+	output := ""
+	if err != nil {
+		fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+		output = err.Error()
+	}
+	r.history.Add(cmd, output)
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+func (r *REPL) undoTrash() {
+	entry, err := trash.Undo()
+	if entry != nil {
+		fmt.Printf("Restored deletion from %s: %s\n", entry.ID, entry.Command)
+	}
+	if err != nil {
+		fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+	}
+}
+
+func (r *REPL) handleTrash(args []string) {
+	if len(args) == 0 {
+		r.listTrash()
+		return
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "on", "off":
+		r.trashMode = strings.ToLower(args[0]) == "on"
+		r.global.trashMode = r.trashMode
+		if err := config.SaveTrashMode(r.trashMode); err != nil {
+			fmt.Printf("%sError saving config: %s%s\n", colorRed, err, colorReset)
+		}
+		state := "off"
+		if r.trashMode {
+			state = "on"
+		}
+		fmt.Printf("Trash mode: %s%s%s\n", colorYellow, state, colorReset)
+		r.noteOverride(r.profile != nil && r.profile.Trash != nil)
+	case "purge":
+		id := ""
+		if len(args) > 1 {
+			id = args[1]
+		} else {
+			fmt.Print("Permanently delete everything in the trash? (y/N): ")
+			input, _ := r.reader.ReadString('\n')
+			if strings.ToLower(strings.TrimSpace(input)) != "y" {
+				fmt.Println("Cancelled.")
+				return
+			}
+		}
+		n, err := trash.Purge(id)
+		if err != nil {
+			fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+			return
+		}
+		fmt.Printf("Purged %d deletion(s) from the trash.\n", n)
+	default:
+		fmt.Printf("%sUsage: .trash [on|off|purge [id]]%s\n", colorRed, colorReset)
+	}
+}
+
+func (r *REPL) listTrash() {
+	state := "off"
+	if r.trashMode {
+		state = "on"
+	}
+	fmt.Printf("Trash mode: %s%s%s\n", colorYellow, state, colorReset)
+
+	entries, err := trash.List()
+	if err != nil {
+		fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+		return
+	}
+	if len(entries) == 0 {
+		fmt.Println("Trash is empty.")
+		return
+	}
+	for _, e := range entries {
+		fmt.Printf("  %s%s%s  %d item(s), %s  %s\n", colorYellow, e.ID, colorReset, len(e.Items), formatSize(e.Size()), e.Command)
+	}
+}
@@ -0,0 +1,137 @@
+package repl
+
+import (
+	"fmt"
+
+	"github.com/markymn/nlcli/internal/config"
+	"github.com/markymn/nlcli/internal/usage"
+)
+
+// showUsage prints token counts and cost per provider/model for this session,
+// today and this month, or per day with .usage days.
+func (r *REPL) showUsage(args []string) {
+	if len(args) > 0 && args[0] == "days" {
+		days, totals, err := r.ledger.Days()
+		if err != nil {
+			fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+			return
+		}
+		if len(days) == 0 {
+			fmt.Println("No usage recorded yet.")
+			return
+		}
+		fmt.Printf("  %-12s %8s %12s %12s %10s\n", "Day", "Requests", "Input", "Output", "Cost")
+		for i, day := range days {
+			printTotals(12, day, totals[i])
+		}
+		return
+	}
+	if len(args) > 0 {
+		fmt.Printf("%sUsage: .usage [days]%s\n", colorRed, colorReset)
+		return
+	}
+
+	today, err := r.ledger.Since(r.ledger.Today())
+	if err != nil {
+		fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+		return
+	}
+	month, err := r.ledger.Since(r.ledger.ThisMonth())
+	if err != nil {
+		fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+		return
+	}
+
+	for _, period := range []struct {
+		title string
+		table usage.Table
+	}{{"This session", r.ledger.Session()}, {"Today", today}, {"This month", month}} {
+		fmt.Printf("\n%s%s%s\n", colorBold, period.title, colorReset)
+		if len(period.table) == 0 {
+			fmt.Println("  no requests")
+			continue
+		}
+		fmt.Printf("  %-40s %8s %12s %12s %10s\n", "Provider / model", "Requests", "Input", "Output", "Cost")
+		for _, key := range period.table.Keys() {
+			provider, model := usage.SplitKey(key)
+			printTotals(40, provider+" "+model, period.table[key])
+		}
+		printTotals(40, "Total", period.table.Sum())
+	}
+
+	fmt.Printf("\nPrices are USD per million tokens, from %s if present. Stored in %s\n", config.PricesPath(), usage.Path())
+}
+
+func printTotals(width int, label string, t usage.Totals) {
+	cost := fmt.Sprintf("$%.4f", t.Cost)
+	if t.Unpriced > 0 {
+		cost += fmt.Sprintf(" (+%d unpriced)", t.Unpriced)
+	}
+	fmt.Printf("  %-*s %8d %12d %12d %10s\n", width, label, t.Requests, t.InputTokens, t.OutputTokens, cost)
+}
+
+// checkLedger warns when usage could not be saved.
+func (r *REPL) checkLedger() {
+	if err := r.ledger.Err(); err != nil {
+		fmt.Printf("%sWarning: could not record usage: %s%s\n", colorYellow, err, colorReset)
+	}
+}
+
+// showBudgetNote says when the budget changed how the last request was made.
+func (r *REPL) showBudgetNote() bool {
+	note := r.client.BudgetNote()
+	if note != "" {
+		fmt.Printf("  %s(%s)%s\n", colorYellow, note, colorReset)
+	}
+	return note != ""
+}
+
+// loadBudget reads budget.toml into the REPL and the client.
+func (r *REPL) loadBudget() {
+	budget, err := config.LoadBudget()
+	if err != nil {
+		fmt.Printf("%sWarning: %s; no budget is enforced%s\n", colorYellow, err, colorReset)
+	}
+	r.budget = budget
+	r.client.SetBudget(budget)
+}
+
+// showBudget reloads budget.toml and shows the limits next to what has been
+// spent so far.
+func (r *REPL) showBudget() {
+	r.loadBudget()
+	b := r.budget
+	if b == nil {
+		fmt.Printf("No budget set. Limits go in %s, e.g.\n", config.BudgetPath())
+		fmt.Println(`  daily_cost = 1.50
+  monthly_tokens = 5000000
+  requests_per_minute = 10
+  action = "downgrade"   # warn, downgrade or refuse`)
+		return
+	}
+
+	today, err := r.ledger.Since(r.ledger.Today())
+	if err != nil {
+		fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+		return
+	}
+	month, _ := r.ledger.Since(r.ledger.ThisMonth())
+	day, mon := today.Sum(), month.Sum()
+
+	fmt.Printf("Budget:  %s%s%s (action: %s)\n", colorYellow, b.Path, colorReset, b.Action)
+	limit := func(label, used string, set bool, max string) {
+		if set {
+			fmt.Printf("  %-20s %14s of %s\n", label, used, max)
+		}
+	}
+	limit("Cost today", fmt.Sprintf("$%.2f", day.Cost), b.DailyCost > 0, fmt.Sprintf("$%.2f", b.DailyCost))
+	limit("Cost this month", fmt.Sprintf("$%.2f", mon.Cost), b.MonthlyCost > 0, fmt.Sprintf("$%.2f", b.MonthlyCost))
+	limit("Tokens today", fmt.Sprint(day.InputTokens+day.OutputTokens), b.DailyTokens > 0, fmt.Sprint(b.DailyTokens))
+	limit("Tokens this month", fmt.Sprint(mon.InputTokens+mon.OutputTokens), b.MonthlyTokens > 0, fmt.Sprint(b.MonthlyTokens))
+	if b.RequestsPerMinute > 0 {
+		fmt.Printf("  %-20s %14d\n", "Requests per minute", b.RequestsPerMinute)
+	}
+	if reason, _, err := r.ledger.Check(*b); err == nil && reason != "" {
+		fmt.Printf("%sOver budget: %s%s\n", colorRed, reason, colorReset)
+	}
+}
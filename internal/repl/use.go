@@ -0,0 +1,97 @@
+package repl
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/markymn/nlcli/internal/config"
+	"github.com/markymn/nlcli/internal/provider"
+)
+
+// handleUse switches to another provider, and optionally model, whose key is
+// already stored: .use anthropic or .use openai/gpt-4o.
+func (r *REPL) handleUse(args []string) {
+	if len(args) == 0 {
+		r.listProviders()
+		return
+	}
+
+	name, model, _ := strings.Cut(args[0], "/")
+	name = strings.ToLower(name)
+	if !slices.Contains(provider.Names, name) {
+		fmt.Printf("%sUnknown provider %q (one of %s)%s\n", colorRed, name, strings.Join(provider.Names, ", "), colorReset)
+		return
+	}
+	if err := provider.Allowed(name); err != nil {
+		fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+		return
+	}
+	var key string
+	if provider.NeedsKey(name) {
+		var err error
+		if key, err = config.LoadProviderKey(name); err != nil {
+			fmt.Printf("%sError: %s; add one with .api%s\n", colorRed, err, colorReset)
+			return
+		}
+	}
+	if model == "" {
+		model, _ = config.LoadProviderModel(name)
+	}
+	if model == "" {
+		model = provider.GetModels(name)[0]
+	}
+
+	if err := config.SaveProviderModel(name, model); err != nil {
+		fmt.Printf("%sError saving config: %s%s\n", colorRed, err, colorReset)
+		return
+	}
+	if err := config.SaveProvider(name); err != nil {
+		fmt.Printf("%sError saving config: %s%s\n", colorRed, err, colorReset)
+		return
+	}
+	r.switchClient(key, model, name)
+}
+
+func (r *REPL) listProviders() {
+	names, err := config.StoredProviders()
+	if err != nil {
+		fmt.Printf("%sError: %s%s\n", colorRed, err, colorReset)
+		return
+	}
+	if len(names) == 0 {
+		fmt.Println("No API keys stored. Add one with .api")
+		return
+	}
+	for _, name := range names {
+		mark := " "
+		if name == r.client.ProviderName() {
+			mark = "*"
+		}
+		model, _ := config.LoadProviderModel(name)
+		fmt.Printf(" %s %-10s %s\n", mark, name, model)
+	}
+	fmt.Println("Usage: .use <provider>[/<model>]")
+}
+
+// switchClient replaces the client. The verifier model belongs to the old
+// provider, so it is turned off when the provider changes.
+func (r *REPL) switchClient(key, model, providerName string) {
+	previous := r.client.ProviderName()
+	r.client = provider.NewMultiClient(key, model, providerName, nil)
+	fmt.Printf("Switched to %s (%s)\n", r.client.PrimaryName(), r.client.PrimaryModel())
+
+	if providerName != previous && config.LoadVerifierModel() != "" {
+		config.SaveVerifierModel("")
+		fmt.Println("Verifier turned off; pick a new one with .verify <model>")
+	}
+	r.configureClient()
+}
+
+// configureClient applies the settings kept outside the client to a new one.
+func (r *REPL) configureClient() {
+	r.client.SetVerifier(config.LoadVerifierModel())
+	r.client.SetLedger(r.ledger)
+	r.client.SetBudget(r.budget)
+	r.applyChain()
+}
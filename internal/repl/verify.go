@@ -0,0 +1,67 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/markymn/nlcli/internal/config"
+	"github.com/markymn/nlcli/internal/shell"
+)
+
+// flagObjection marks audit records of commands the verifier objected to.
+const flagObjection = "verifier-objection"
+
+// review asks the verifier model about cmd and returns its objection, or ""
+// when it agrees. It objects when the command does not match the request,
+// when it calls destructive a command the static checks consider harmless,
+// and when it cannot give an answer at all, so a failing verifier never
+// lets a command through unchecked.
+func (r *REPL) review(input, cmd string, risk shell.Risk) string {
+	review, err := r.client.Verify(input, cmd, r.shellType)
+	if err != nil {
+		return fmt.Sprintf("unavailable: %s", err)
+	}
+	if review == nil {
+		return ""
+	}
+
+	reason := review.Reason
+	if reason == "" {
+		reason = "no reason given"
+	}
+	switch {
+	case !review.Matches:
+		return fmt.Sprintf("%s: does not match the request: %s", review.Model, reason)
+	case review.Destructive && risk == shell.RiskNone:
+		return fmt.Sprintf("%s: considers this destructive: %s", review.Model, reason)
+	}
+	return ""
+}
+
+func (r *REPL) showObjection(objection string) {
+	if objection != "" {
+		fmt.Printf("  %s%sVerifier %s%s\n", colorBold, colorRed, objection, colorReset)
+	}
+}
+
+func (r *REPL) changeVerifier(args []string) {
+	if len(args) == 0 {
+		model := r.client.VerifierModel()
+		if model == "" {
+			model = "off"
+		}
+		fmt.Printf("Verifier: %s%s%s\n", colorYellow, model, colorReset)
+		fmt.Println("Usage: .verify <model|off>")
+		return
+	}
+
+	model := args[0]
+	if strings.EqualFold(model, "off") {
+		model = ""
+	}
+	if err := config.SaveVerifierModel(model); err != nil {
+		fmt.Printf("%sError saving config: %s%s\n", colorRed, err, colorReset)
+	}
+	r.client.SetVerifier(model)
+	r.changeVerifier(nil)
+}
@@ -0,0 +1,330 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// word is a token of a command line. text has the quotes removed; raw is
+// the word as typed, so a rebuilt line keeps its quoting and expansions.
+type word struct {
+	text   string
+	raw    string
+	quoted bool
+	op     bool
+	ctrl   bool
+}
+
+// tokenize splits a command line into words. Quotes are removed, and
+// redirection and control operators are kept as op words so callers can find
+// redirect targets and rebuild the line.
+func tokenize(cmd string) []word {
+	var words []word
+	var buf strings.Builder
+	inWord, quoted := false, false
+	runes := []rune(cmd)
+	i, start := 0, 0
+
+	flushWord := func() {
+		if inWord {
+			raw := string(runes[start:min(i, len(runes))])
+			words = append(words, word{text: buf.String(), raw: raw, quoted: quoted})
+		}
+		buf.Reset()
+		inWord, quoted = false, false
+	}
+	control := func(op string) {
+		flushWord()
+		words = append(words, word{text: op, op: true, ctrl: true})
+	}
+
+	for ; i < len(runes); i++ {
+		c := runes[i]
+		if !inWord {
+			start = i
+		}
+		switch {
+		case c == '\'':
+			inWord, quoted = true, true
+			for i++; i < len(runes) && runes[i] != '\''; i++ {
+				buf.WriteRune(runes[i])
+			}
+		case c == '"':
+			inWord, quoted = true, true
+			for i++; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune(`"\$`+"`", runes[i+1]) {
+					i++
+				}
+				buf.WriteRune(runes[i])
+			}
+		case c == '\\' && i+1 < len(runes) && strings.ContainsRune(" \t'\"\\$;|&<>()", runes[i+1]):
+			i++
+			inWord = true
+			buf.WriteRune(runes[i])
+		case c == ' ' || c == '\t':
+			flushWord()
+		case c == '\n' || c == ';':
+			control(string(c))
+		case c == '|' || c == '&':
+			if c == '&' && i+1 < len(runes) && runes[i+1] == '>' {
+				flushWord()
+				op := "&>"
+				i++
+				if i+1 < len(runes) && runes[i+1] == '>' {
+					op = "&>>"
+					i++
+				}
+				words = append(words, word{text: op, op: true})
+				continue
+			}
+			op := string(c)
+			if i+1 < len(runes) && runes[i+1] == c {
+				i++
+				op += string(c)
+			}
+			control(op)
+		case c == '>' || c == '<':
+			op := string(c)
+			if inWord && !quoted && isDigits(buf.String()) {
+				op = buf.String() + op
+				buf.Reset()
+				inWord = false
+			}
+			flushWord()
+			if i+1 < len(runes) && (runes[i+1] == '>' || runes[i+1] == '&' || runes[i+1] == '|') {
+				i++
+				op += string(runes[i])
+			}
+			words = append(words, word{text: op, op: true})
+		default:
+			inWord = true
+			buf.WriteRune(c)
+		}
+	}
+	flushWord()
+	return words
+}
+
+// splitCommand breaks a command line into its list/pipeline segments.
+func splitCommand(cmd string) [][]word {
+	var segments [][]word
+	for _, span := range segmentSpans(tokenize(cmd)) {
+		segments = append(segments, span.words)
+	}
+	return segments
+}
+
+type span struct {
+	start int
+	words []word
+}
+
+func segmentSpans(tokens []word) []span {
+	var spans []span
+	start := 0
+	for i := 0; i <= len(tokens); i++ {
+		if i < len(tokens) && !tokens[i].ctrl {
+			continue
+		}
+		if i > start {
+			spans = append(spans, span{start: start, words: tokens[start:i]})
+		}
+		start = i + 1
+	}
+	return spans
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Segments splits cmd at pipes and list operators and returns each command
+// with its words joined by single spaces. Commands behind wrappers like sudo
+// are returned a second time without the wrapper.
+func Segments(cmd string) []string {
+	var out []string
+	for _, seg := range splitCommand(cmd) {
+		plain := plainWords(seg)
+		if len(plain) == 0 {
+			continue
+		}
+		out = append(out, joinTexts(plain))
+		if inner := unwrapCommand(plain); len(inner) > 0 && len(inner) != len(plain) {
+			out = append(out, joinTexts(inner))
+		}
+	}
+	return out
+}
+
+func joinTexts(words []word) string {
+	texts := make([]string, len(words))
+	for i, w := range words {
+		texts[i] = w.text
+	}
+	return strings.Join(texts, " ")
+}
+
+// commandWords drops leading environment assignments, redirections and
+// wrappers like sudo, env or nohup from a segment, leaving the command that
+// actually runs followed by its arguments.
+func commandWords(seg []word) []word {
+	return unwrapCommand(plainWords(seg))
+}
+
+func plainWords(seg []word) []word {
+	var out []word
+	for i := 0; i < len(seg); i++ {
+		w := seg[i]
+		if w.op {
+			i++
+			continue
+		}
+		if len(out) == 0 && !w.quoted && isAssignment(w.text) {
+			continue
+		}
+		out = append(out, w)
+	}
+	return out
+}
+
+// Wrappers that run the rest of their arguments as a command, with the flags
+// of each that take a separate value.
+var wrapperCommands = map[string]map[string]bool{
+	"sudo":    {"-u": true, "-g": true, "-C": true, "-D": true, "-h": true, "-p": true, "-r": true, "-t": true, "-U": true},
+	"doas":    {"-u": true, "-C": true},
+	"pkexec":  {"--user": true},
+	"gsudo":   {"-u": true, "--user": true},
+	"env":     {"-u": true, "-C": true, "-S": true, "--unset": true, "--chdir": true},
+	"nice":    {"-n": true, "--adjustment": true},
+	"nohup":   {},
+	"time":    {"-f": true, "-o": true},
+	"command": {},
+	"exec":    {"-a": true},
+	"timeout": {"-s": true, "-k": true, "--signal": true, "--kill-after": true},
+}
+
+func unwrapCommand(words []word) []word {
+	for len(words) > 0 {
+		next, ok := unwrapOnce(words)
+		if !ok {
+			return words
+		}
+		words = next
+	}
+	return words
+}
+
+// unwrapOnce strips one wrapper from the front of words. su -c and runas take
+// the command as a single string, which is tokenized in turn.
+func unwrapOnce(words []word) ([]word, bool) {
+	name := baseName(words[0].text)
+	switch name {
+	case "su":
+		for i := 1; i < len(words); i++ {
+			t := words[i].text
+			if (t == "-c" || t == "--command") && i+1 < len(words) {
+				return innerCommand(words[i+1].text), true
+			}
+			if v, ok := strings.CutPrefix(t, "--command="); ok {
+				return innerCommand(v), true
+			}
+		}
+		return nil, true
+	case "runas":
+		args := nonFlags(words[1:])
+		if len(args) == 0 {
+			return nil, true
+		}
+		return innerCommand(args[len(args)-1].text), true
+	}
+
+	valueFlags, ok := wrapperCommands[name]
+	if !ok {
+		return words, false
+	}
+	i := 1
+	for i < len(words) {
+		t := words[i].text
+		if t == "--" {
+			i++
+			break
+		}
+		if strings.HasPrefix(t, "-") && len(t) > 1 {
+			if valueFlags[t] {
+				i++
+			}
+			i++
+			continue
+		}
+		if name == "env" && isAssignment(t) {
+			i++
+			continue
+		}
+		break
+	}
+	// timeout takes the duration before the command
+	if name == "timeout" && i < len(words) {
+		i++
+	}
+	return words[min(i, len(words)):], true
+}
+
+func innerCommand(s string) []word {
+	segs := splitCommand(s)
+	if len(segs) == 0 {
+		return nil
+	}
+	return plainWords(segs[0])
+}
+
+func isAssignment(s string) bool {
+	eq := strings.Index(s, "=")
+	if eq <= 0 {
+		return false
+	}
+	for _, c := range s[:eq] {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func baseName(name string) string {
+	name = strings.ToLower(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	return strings.TrimSuffix(name, ".exe")
+}
+
+// expandPath resolves ~ and environment variables, then expands globs unless
+// the word was quoted. Patterns that match nothing are returned unchanged.
+func expandPath(w word) []string {
+	p := w.text
+	if strings.Contains(p, "$(") || strings.Contains(p, "`") {
+		return []string{p}
+	}
+	if p == "~" || strings.HasPrefix(p, "~/") || strings.HasPrefix(p, "~\\") {
+		if home, err := os.UserHomeDir(); err == nil {
+			p = home + p[1:]
+		}
+	}
+	if !w.quoted {
+		p = os.ExpandEnv(p)
+	}
+	if w.quoted || !strings.ContainsAny(p, "*?[") {
+		return []string{p}
+	}
+	matches, err := filepath.Glob(p)
+	if err != nil || len(matches) == 0 {
+		return []string{p}
+	}
+	return matches
+}
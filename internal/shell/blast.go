@@ -0,0 +1,334 @@
+package shell
+
+import (
+	"bufio"
+	"context"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+const blastWalkLimit = 100000
+
+// blastFindTimeout bounds how long find may search for the paths a command
+// would delete before the prompt is shown.
+const blastFindTimeout = 3 * time.Second
+
+type BlastTarget struct {
+	Path    string
+	Files   int
+	Dirs    int
+	Size    int64
+	Missing bool
+}
+
+type BlastRadius struct {
+	Command   string
+	Targets   []BlastTarget
+	Files     int
+	Dirs      int
+	TotalSize int64
+	Truncated bool
+}
+
+var removeCommands = map[string]bool{
+	"rm": true, "rmdir": true, "unlink": true, "shred": true,
+	"del": true, "erase": true, "rd": true,
+	"remove-item": true, "ri": true,
+}
+
+var moveCommands = map[string]bool{
+	"mv": true, "move": true, "move-item": true, "mi": true,
+}
+
+var permCommands = map[string]bool{
+	"chmod": true, "chown": true, "chgrp": true,
+}
+
+// ComputeBlastRadius expands the path arguments of destructive commands (rm, mv,
+// chmod -R, find -delete and friends) and sums up what they would touch. It
+// returns nil when cmd contains no command it knows how to analyze.
+func ComputeBlastRadius(cmd string) *BlastRadius {
+	var br *BlastRadius
+	for _, seg := range splitCommand(cmd) {
+		words := commandWords(seg)
+		if len(words) == 0 {
+			continue
+		}
+		name := baseName(words[0].text)
+		args := words[1:]
+
+		var targets []word
+		recursive := false
+		switch {
+		case removeCommands[name]:
+			targets = nonFlags(args)
+			recursive = name != "rm" || hasFlag(args, "r", "R", "recursive", "recurse")
+		case moveCommands[name]:
+			targets = moveTargets(args)
+			recursive = true
+		case permCommands[name]:
+			targets = nonFlags(args)
+			if len(targets) > 0 {
+				targets = targets[1:]
+			}
+			recursive = hasFlag(args, "R", "recursive")
+		case name == "find":
+			paths, truncated, ok := findDeletions(args)
+			if !ok {
+				continue
+			}
+			if br == nil {
+				br = &BlastRadius{Command: cmd}
+			}
+			br.Truncated = br.Truncated || truncated
+			for _, p := range paths {
+				br.add(p, false)
+			}
+			continue
+		default:
+			continue
+		}
+
+		if len(targets) == 0 {
+			continue
+		}
+		if br == nil {
+			br = &BlastRadius{Command: cmd}
+		}
+		for _, t := range targets {
+			for _, p := range expandPath(t) {
+				br.add(p, recursive)
+			}
+		}
+	}
+	return br
+}
+
+func (b *BlastRadius) add(path string, recursive bool) {
+	t := BlastTarget{Path: path}
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Missing = true
+		b.Targets = append(b.Targets, t)
+		return
+	}
+
+	if !info.IsDir() {
+		t.Files = 1
+		t.Size = info.Size()
+	} else if !recursive {
+		t.Dirs = 1
+	} else {
+		filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if b.Files+b.Dirs+t.Files+t.Dirs >= blastWalkLimit {
+				b.Truncated = true
+				return filepath.SkipAll
+			}
+			if d.IsDir() {
+				t.Dirs++
+				return nil
+			}
+			t.Files++
+			if fi, err := d.Info(); err == nil {
+				t.Size += fi.Size()
+			}
+			return nil
+		})
+	}
+
+	b.Targets = append(b.Targets, t)
+	b.Files += t.Files
+	b.Dirs += t.Dirs
+	b.TotalSize += t.Size
+}
+
+// Largest returns up to n targets ordered by size, biggest first.
+func (b *BlastRadius) Largest(n int) []BlastTarget {
+	sorted := make([]BlastTarget, len(b.Targets))
+	copy(sorted, b.Targets)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Size > sorted[j].Size
+	})
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// findDeletions lists what a find command would remove by re-running it with
+// -delete (or -exec rm) swapped for -print. Anything else with side effects is
+// left alone. find is stopped after blastFindTimeout or blastWalkLimit paths,
+// and truncated reports that the list is incomplete.
+func findDeletions(args []word) (paths []string, truncated, ok bool) {
+	if runtime.GOOS == "windows" {
+		return nil, false, false
+	}
+
+	var out []string
+	deletes := false
+	for i := 0; i < len(args); i++ {
+		a := args[i].text
+		switch a {
+		case "-delete":
+			deletes = true
+			continue
+		case "-exec", "-execdir":
+			end := i + 1
+			for end < len(args) && args[end].text != ";" && args[end].text != "+" {
+				end++
+			}
+			if i+1 < len(args) && removeCommands[baseName(args[i+1].text)] {
+				deletes = true
+				i = end
+				continue
+			}
+			return nil, false, false
+		case "-ok", "-okdir", "-fprint", "-fprint0", "-fprintf", "-fls":
+			return nil, false, false
+		}
+		out = append(out, a)
+	}
+	if !deletes {
+		return nil, false, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), blastFindTimeout)
+	defer cancel()
+	c := exec.CommandContext(ctx, "find", append(out, "-print")...)
+	stdout, err := c.StdoutPipe()
+	if err != nil {
+		return nil, false, false
+	}
+	if err := c.Start(); err != nil {
+		return nil, false, false
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		if len(paths) >= blastWalkLimit {
+			truncated = true
+			break
+		}
+		paths = append(paths, scanner.Text())
+	}
+	// kills find if it is still searching
+	cancel()
+	c.Wait()
+	if ctx.Err() == context.DeadlineExceeded {
+		truncated = true
+	}
+	if len(paths) == 0 && !truncated {
+		// find failed or matched nothing; either way nothing to show
+		return nil, false, c.ProcessState != nil && c.ProcessState.Success()
+	}
+	return paths, truncated, true
+}
+
+// moveTargets keeps the sources of a move plus the existing files at the
+// destination they would overwrite. The destination directory itself, and
+// directories in it, are not affected: mv refuses to replace a non-empty
+// directory.
+func moveTargets(args []word) []word {
+	var dest *word
+	var rest []word
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case !a.quoted && (a.text == "-t" || a.text == "--target-directory") && i+1 < len(args):
+			dest = &args[i+1]
+			i++
+		case !a.quoted && strings.HasPrefix(a.text, "--target-directory="):
+			dest = &word{text: strings.TrimPrefix(a.text, "--target-directory=")}
+		default:
+			rest = append(rest, a)
+		}
+	}
+	sources := nonFlags(rest)
+	if dest == nil {
+		if len(sources) < 2 {
+			return sources
+		}
+		dest, sources = &sources[len(sources)-1], sources[:len(sources)-1]
+	}
+
+	paths := expandPath(*dest)
+	if len(paths) != 1 {
+		return sources
+	}
+	info, err := os.Stat(paths[0])
+	if err != nil {
+		return sources
+	}
+	if !info.IsDir() {
+		return append(sources, *dest)
+	}
+
+	out := append([]word{}, sources...)
+	for _, src := range sources {
+		for _, p := range expandPath(src) {
+			target := filepath.Join(paths[0], filepath.Base(p))
+			if fi, err := os.Lstat(target); err == nil && !fi.IsDir() {
+				out = append(out, word{text: target, quoted: true})
+			}
+		}
+	}
+	return out
+}
+
+func nonFlags(args []word) []word {
+	var out []word
+	endOfFlags := false
+	for _, a := range args {
+		if !endOfFlags && a.text == "--" {
+			endOfFlags = true
+			continue
+		}
+		if !endOfFlags && !a.quoted && isFlag(a.text) {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+func isFlag(s string) bool {
+	if len(s) < 2 {
+		return false
+	}
+	if s[0] == '-' {
+		return true
+	}
+	return s[0] == '/' && runtime.GOOS == "windows" && len(s) <= 3
+}
+
+// hasFlag reports whether any of the given short (single letter) or long flags
+// is present. Short flags may be bundled, as in -rf.
+func hasFlag(args []word, flags ...string) bool {
+	for _, a := range args {
+		s := a.text
+		if a.quoted || !strings.HasPrefix(s, "-") || s == "-" || s == "--" {
+			continue
+		}
+		for _, f := range flags {
+			if len(f) == 1 {
+				if !strings.HasPrefix(s, "--") && strings.Contains(s[1:], f) {
+					return true
+				}
+				continue
+			}
+			if strings.EqualFold(strings.TrimLeft(s, "-"), f) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestComputeBlastRadius(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.log", "b.log", "keep.txt", "sub/c.log", "sub/d.log", "dst/a.log", "dst/sub/e.log", "dst/big/f.log"} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("12345"), 0644)
+	}
+
+	tests := []struct {
+		name      string
+		cmd       string
+		wantNil   bool
+		wantFiles int
+		wantSize  int64
+	}{
+		{name: "ls", cmd: "ls -la", wantNil: true},
+		{name: "rm glob", cmd: "rm " + dir + "/*.log", wantFiles: 2, wantSize: 10},
+		{name: "rm quoted glob", cmd: "rm '" + dir + "/*.log'", wantFiles: 0},
+		{name: "rm dir not recursive", cmd: "rm " + dir + "/sub", wantFiles: 0},
+		{name: "rm -rf dir", cmd: "rm -rf " + dir + "/sub", wantFiles: 2, wantSize: 10},
+		{name: "chmod -R", cmd: "chmod -R 755 " + dir, wantFiles: 8, wantSize: 40},
+		{name: "mv into dir", cmd: "mv " + dir + "/b.log " + dir + "/dst", wantFiles: 1, wantSize: 5},
+		{name: "mv overwrites file in dir", cmd: "mv " + dir + "/a.log " + dir + "/dst", wantFiles: 2, wantSize: 10},
+		{name: "mv dir next to dir", cmd: "mv " + dir + "/sub " + dir + "/dst", wantFiles: 2, wantSize: 10},
+		{name: "mv -t", cmd: "mv -t " + dir + "/dst " + dir + "/a.log " + dir + "/keep.txt", wantFiles: 3, wantSize: 15},
+		{name: "mv onto file", cmd: "mv " + dir + "/b.log " + dir + "/keep.txt", wantFiles: 2, wantSize: 10},
+		{name: "find -delete", cmd: "find " + dir + "/sub -name '*.log' -delete", wantFiles: 2, wantSize: 10},
+		{name: "after pipe", cmd: "echo hi && rm " + dir + "/keep.txt", wantFiles: 1, wantSize: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			br := ComputeBlastRadius(tt.cmd)
+			if tt.wantNil {
+				if br != nil {
+					t.Fatalf("ComputeBlastRadius(%q) = %+v, want nil", tt.cmd, br)
+				}
+				return
+			}
+			if br == nil {
+				t.Fatalf("ComputeBlastRadius(%q) = nil", tt.cmd)
+			}
+			if br.Files != tt.wantFiles || br.TotalSize != tt.wantSize {
+				t.Errorf("ComputeBlastRadius(%q) = %d files, %d bytes, want %d files, %d bytes",
+					tt.cmd, br.Files, br.TotalSize, tt.wantFiles, tt.wantSize)
+			}
+		})
+	}
+}
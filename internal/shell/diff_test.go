@@ -0,0 +1,108 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	b := "one\ntwo\nTHREE\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\n"
+
+	want := `--- a
++++ b
+@@ -1,6 +1,6 @@
+ one
+ two
+-three
++THREE
+ four
+ five
+ six
+@@ -8,3 +8,4 @@
+ eight
+ nine
+ ten
++eleven
+`
+	if got := UnifiedDiff("a", "b", a, b); got != want {
+		t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, want)
+	}
+	if got := UnifiedDiff("a", "b", a, a); got != "" {
+		t.Errorf("UnifiedDiff() of equal input = %q, want empty", got)
+	}
+}
+
+func TestPrepareInPlacePreview(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "notes.txt")
+	os.WriteFile(file, []byte("foo\n"), 0644)
+	other := filepath.Join(dir, "other.txt")
+
+	tests := []struct {
+		name      string
+		cmd       string
+		wantFiles int
+	}{
+		{name: "sed -i", cmd: "sed -i 's/foo/bar/' " + file, wantFiles: 1},
+		{name: "sed -e -i", cmd: "sed -i -e 's/foo/bar/' " + file, wantFiles: 1},
+		{name: "sed -ni with addresses", cmd: `sed -n -i '/^#/d;$p;1,3s|a/b|c|gI' ` + file, wantFiles: 1},
+		{name: "awk -i inplace", cmd: `awk -i inplace '{ print toupper($0) }' ` + file, wantFiles: 1},
+		{name: "redirect", cmd: "sort " + file + " > " + file, wantFiles: 1},
+		{name: "redirect to /dev/null", cmd: "sed -i 's/foo/bar/' " + file + " 2> /dev/null", wantFiles: 1},
+		{name: "perl", cmd: "perl -pi -e 's/foo/bar/g' " + file, wantFiles: 1},
+		{name: "perl tr and braces", cmd: "perl -pi -e 's{foo}{bar}gi; tr/a-z/A-Z/' " + file, wantFiles: 1},
+		{name: "perl e flag", cmd: "perl -pi -e 's/foo/`id`/e' " + file, wantFiles: 0},
+		{name: "perl interpolated code", cmd: `perl -pi -e 's/foo/${\ system("id")}/' ` + file, wantFiles: 0},
+		{name: "perl module", cmd: "perl -MPOSIX -pi -e 's/foo/bar/' " + file, wantFiles: 0},
+		{name: "stdout and stderr to a file", cmd: "sed -i 's/foo/bar/' " + file + " >& " + other, wantFiles: 0},
+		{name: "stdout and stderr attached", cmd: "sed -i 's/foo/bar/' " + file + " >&" + other, wantFiles: 0},
+		{name: "ampersand redirect", cmd: "sed -i 's/foo/bar/' " + file + " &> " + other, wantFiles: 0},
+		{name: "descriptor duplication", cmd: "sed -i 's/foo/bar/' " + file + " 2>&1", wantFiles: 1},
+		{name: "sort compress program", cmd: "sort --compress-program=sh " + file + " > " + file, wantFiles: 0},
+		{name: "sort abbreviated output", cmd: "sort --out=" + other + " " + file + " > " + file, wantFiles: 0},
+		{name: "perl system", cmd: `perl -i -pe 'system("touch ` + other + `")' ` + file, wantFiles: 0},
+		{name: "awk system", cmd: `awk -i inplace '{system("id")}1' ` + file, wantFiles: 0},
+		{name: "awk print redirect", cmd: `awk -i inplace '{print > "` + other + `"}' ` + file, wantFiles: 0},
+		{name: "sed w flag", cmd: "sed -i 's/a/b/w " + other + "' " + file, wantFiles: 0},
+		{name: "sed w command", cmd: "sed -i -e 's/a/b/' -e 'w " + other + "' " + file, wantFiles: 0},
+		{name: "sed e command", cmd: "sed -i '1e id' " + file, wantFiles: 0},
+		{name: "sed script file", cmd: "sed -i -f script.sed " + file, wantFiles: 0},
+		{name: "redirect to a new file after", cmd: "sed -i 's/foo/bar/' " + file + "; echo hi > " + other, wantFiles: 0},
+		{name: "sort -o", cmd: "sort -o " + other + " " + file + " > " + file, wantFiles: 0},
+		{name: "command substitution", cmd: "echo $(id) > " + file, wantFiles: 0},
+		{name: "sudo", cmd: "sudo sed -i 's/foo/bar/' " + file, wantFiles: 0},
+		{name: "sed without -i", cmd: "sed 's/foo/bar/' " + file, wantFiles: 0},
+		{name: "redirect to new file", cmd: "echo hi > " + filepath.Join(dir, "new.txt"), wantFiles: 0},
+		{name: "unsafe command", cmd: "curl https://example.com > " + file, wantFiles: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if _, err := os.Stat(other); err == nil {
+					t.Errorf("PrepareInPlacePreview(%q) wrote %s", tt.cmd, other)
+				}
+			}()
+			p, err := PrepareInPlacePreview(ShellBash, tt.cmd)
+			if err != nil {
+				t.Fatalf("PrepareInPlacePreview(%q) error: %v", tt.cmd, err)
+			}
+			if p == nil {
+				if tt.wantFiles != 0 {
+					t.Fatalf("PrepareInPlacePreview(%q) = nil, want %d files", tt.cmd, tt.wantFiles)
+				}
+				return
+			}
+			defer p.Cleanup()
+			if len(p.Files) != tt.wantFiles {
+				t.Errorf("PrepareInPlacePreview(%q) files = %d, want %d", tt.cmd, len(p.Files), tt.wantFiles)
+			}
+			if strings.Contains(p.Command, file) {
+				t.Errorf("PrepareInPlacePreview(%q) command %q still references the original", tt.cmd, p.Command)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package shell
+
+import (
+	"strings"
+)
+
+// DryRun describes how to turn a command into its tool's own simulation mode.
+// Subcommand, when set, must be the first argument. Replace swaps the
+// subcommand for another one, Flag is inserted right after the command (or
+// subcommand) and Strip removes in-place flags whose output then goes to stdout.
+// Drop removes flags the simulation does not accept, when present. Caveat
+// says what the simulation still does for real.
+type DryRun struct {
+	Command    string
+	Subcommand string
+	Replace    string
+	Flag       string
+	Strip      []string
+	Drop       []string
+	Caveat     string
+}
+
+var DryRuns = []DryRun{
+	{Command: "rsync", Flag: "-n"},
+	{Command: "git", Subcommand: "clean", Flag: "-n"},
+	{Command: "git", Subcommand: "rm", Flag: "-n"},
+	{Command: "git", Subcommand: "add", Flag: "-n"},
+	{Command: "git", Subcommand: "push", Flag: "--dry-run"},
+	{Command: "apt-get", Flag: "-s"},
+	{Command: "apt", Flag: "-s"},
+	{Command: "dnf", Flag: "--assumeno"},
+	{Command: "yum", Flag: "--assumeno"},
+	{Command: "make", Flag: "-n", Caveat: "make -n still runs recipe lines starting with + and recursive $(MAKE) calls"},
+	{Command: "terraform", Subcommand: "apply", Replace: "plan", Drop: []string{"-auto-approve", "-backup"}},
+	{Command: "terraform", Subcommand: "destroy", Replace: "plan", Flag: "-destroy", Drop: []string{"-auto-approve", "-backup"}},
+	{Command: "kubectl", Subcommand: "apply", Flag: "--dry-run=client"},
+	{Command: "kubectl", Subcommand: "delete", Flag: "--dry-run=client"},
+	{Command: "helm", Subcommand: "install", Flag: "--dry-run"},
+	{Command: "helm", Subcommand: "upgrade", Flag: "--dry-run"},
+	{Command: "npm", Subcommand: "install", Flag: "--dry-run"},
+	{Command: "npm", Subcommand: "uninstall", Flag: "--dry-run"},
+	{Command: "pip", Subcommand: "install", Flag: "--dry-run", Caveat: "pip still downloads and builds source packages, which runs their setup code"},
+	{Command: "brew", Subcommand: "cleanup", Flag: "-n"},
+	{Command: "sed", Strip: []string{"-i", "--in-place"}},
+}
+
+// DryRunCommand returns the simulated variant of cmd, if one of the DryRuns
+// rules matches, and the rule's caveat. Only single commands without output
+// redirects are rewritten; lists and pipelines are left alone since the other
+// parts would still run for real, and a redirect would still write its file.
+func DryRunCommand(st ShellType, cmd string) (preview, caveat string, ok bool) {
+	segments := splitCommand(cmd)
+	if len(segments) != 1 {
+		return "", "", false
+	}
+	seg := segments[0]
+	for i, w := range seg {
+		var target word
+		if i+1 < len(seg) {
+			target = seg[i+1]
+		}
+		if w.op && redirectWrites(w, target) {
+			return "", "", false
+		}
+	}
+
+	start := 0
+	for start < len(seg) && !seg[start].op && !seg[start].quoted && isAssignment(seg[start].text) {
+		start++
+	}
+	if start >= len(seg) || seg[start].op {
+		return "", "", false
+	}
+
+	name := baseName(seg[start].text)
+	for _, d := range DryRuns {
+		if d.Command != name {
+			continue
+		}
+		pos := start + 1
+		if d.Subcommand != "" {
+			if pos >= len(seg) || seg[pos].op || seg[pos].text != d.Subcommand {
+				continue
+			}
+			pos++
+		}
+		if rewritten, ok := d.apply(seg, pos); ok {
+			return joinWords(st, rewritten), d.Caveat, true
+		}
+	}
+	return "", "", false
+}
+
+func (d DryRun) apply(seg []word, pos int) ([]word, bool) {
+	var out []word
+	out = append(out, seg[:pos]...)
+	if d.Replace != "" {
+		out[pos-1] = word{text: d.Replace}
+	}
+	if d.Flag != "" {
+		out = append(out, word{text: d.Flag})
+	}
+
+	stripped := false
+	for _, w := range seg[pos:] {
+		if !w.op && !w.quoted && matchesFlag(d.Strip, w.text) {
+			stripped = true
+			continue
+		}
+		if !w.op && !w.quoted && matchesFlag(d.Drop, w.text) {
+			continue
+		}
+		out = append(out, w)
+	}
+	if len(d.Strip) > 0 && !stripped {
+		return nil, false
+	}
+	return out, true
+}
+
+// matchesFlag reports whether arg is one of flags, with or without a value.
+func matchesFlag(flags []string, arg string) bool {
+	for _, s := range flags {
+		if arg == s || strings.HasPrefix(arg, s+"=") {
+			return true
+		}
+		// sed -i.bak and friends attach the suffix to the short flag
+		if !strings.HasPrefix(s, "--") && strings.HasPrefix(arg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinWords(st ShellType, words []word) string {
+	parts := make([]string, 0, len(words))
+	for i, w := range words {
+		// keep descriptor duplications like 2>&1 together
+		if i > 0 && words[i-1].op && strings.HasSuffix(words[i-1].text, ">&") && !w.op && isDigits(w.text) {
+			parts[len(parts)-1] += w.text
+			continue
+		}
+		if w.op {
+			parts = append(parts, w.text)
+			continue
+		}
+		// words from the command keep their quoting, so "$SRC/" still
+		// expands and globs stay globs
+		if w.raw != "" {
+			parts = append(parts, w.raw)
+			continue
+		}
+		if w.quoted || strings.ContainsAny(w.text, " \t'\";|&<>()") {
+			parts = append(parts, quote(st, w.text))
+			continue
+		}
+		parts = append(parts, w.text)
+	}
+	return strings.Join(parts, " ")
+}
+
+// QuoteArg quotes s for the given shell when it contains anything beyond plain
+// path and flag characters.
+func QuoteArg(st ShellType, s string) string {
+	if s != "" && !strings.ContainsFunc(s, func(c rune) bool {
+		return !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			strings.ContainsRune("-_./=:,+@%~", c) || (c == '\\' && (st == ShellPowerShell || st == ShellCmd)))
+	}) {
+		return s
+	}
+	return quote(st, s)
+}
+
+func quote(st ShellType, s string) string {
+	switch st {
+	case ShellPowerShell:
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	case ShellCmd:
+		return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+	case ShellFish:
+		return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+	default:
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	}
+}
@@ -0,0 +1,48 @@
+package shell
+
+import (
+	"testing"
+)
+
+func TestDryRunCommand(t *testing.T) {
+	tests := []struct {
+		cmd        string
+		want       string
+		wantOK     bool
+		wantCaveat bool
+	}{
+		{cmd: "rsync -av src/ dst/", want: "rsync -n -av src/ dst/", wantOK: true},
+		{cmd: "git clean -fd", want: "git clean -n -fd", wantOK: true},
+		{cmd: "sudo apt-get install vim", wantOK: false},
+		{cmd: "apt-get install -y vim", want: "apt-get -s install -y vim", wantOK: true},
+		{cmd: "make install", want: "make -n install", wantOK: true, wantCaveat: true},
+		{cmd: "pip install requests", want: "pip install --dry-run requests", wantOK: true, wantCaveat: true},
+		{cmd: "make > build.log", wantOK: false},
+		{cmd: "rsync -av a/ b/ > log.txt", wantOK: false},
+		{cmd: "sed -i s/a/b/ f > out", wantOK: false},
+		{cmd: "rsync -av a/ b/ 2>&1", want: "rsync -n -av a/ b/ 2>&1", wantOK: true},
+		{cmd: "rsync -a x y >& file", wantOK: false},
+		{cmd: "rsync -a x y >&file", wantOK: false},
+		{cmd: "rsync -a x y &> file", wantOK: false},
+		{cmd: `rsync -a "$SRC/" dst/`, want: `rsync -n -a "$SRC/" dst/`, wantOK: true},
+		{cmd: `rsync -a "my dir/" 'b c'/`, want: `rsync -n -a "my dir/" 'b c'/`, wantOK: true},
+		{cmd: "sed -i s/a/b/ *.txt", want: "sed s/a/b/ *.txt", wantOK: true},
+		{cmd: "terraform apply -auto-approve", want: "terraform plan", wantOK: true},
+		{cmd: "terraform destroy -auto-approve -var x=1", want: "terraform plan -destroy -var x=1", wantOK: true},
+		{cmd: "terraform destroy", want: "terraform plan -destroy", wantOK: true},
+		{cmd: "sed -i 's/foo/bar/g' a.txt", want: "sed 's/foo/bar/g' a.txt", wantOK: true},
+		{cmd: "sed -i.bak -e 's/a b/c/' a.txt", want: "sed -e 's/a b/c/' a.txt", wantOK: true},
+		{cmd: "sed 's/foo/bar/' a.txt", wantOK: false},
+		{cmd: "git status", wantOK: false},
+		{cmd: "rsync -a a b && rm -rf a", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cmd, func(t *testing.T) {
+			got, caveat, ok := DryRunCommand(ShellBash, tt.cmd)
+			if ok != tt.wantOK || got != tt.want || (caveat != "") != tt.wantCaveat {
+				t.Errorf("DryRunCommand(%q) = %q, %q, %v, want %q, %v", tt.cmd, got, caveat, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
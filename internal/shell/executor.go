@@ -2,6 +2,7 @@ package shell
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"os/exec"
 	"runtime"
@@ -12,6 +13,7 @@ type Executor struct {
 	shellType ShellType
 	binary    string
 	args      []string
+	secrets   SecretResolver
 }
 
 func NewExecutor(st ShellType) *Executor {
@@ -46,6 +48,10 @@ func NewExecutor(st ShellType) *Executor {
 }
 
 func (e *Executor) Execute(command string) (string, string, error) {
+	command, secretEnv, err := e.resolveSecrets(command)
+	if err != nil {
+		return "", "", err
+	}
 	args := append(e.args, command)
 	cmd := exec.Command(e.binary, args...)
 
@@ -53,22 +59,26 @@ func (e *Executor) Execute(command string) (string, string, error) {
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 	cmd.Stdin = os.Stdin
-	cmd.Env = os.Environ()
+	cmd.Env = append(os.Environ(), secretEnv...)
 	if e.shellType == ShellBash && runtime.GOOS == "windows" {
 		cmd.Env = append(cmd.Env, "MSYS_NO_PATHCONV=1")
 	}
 
-	err := cmd.Run()
+	err = cmd.Run()
 	return stdout.String(), stderr.String(), err
 }
 
 func (e *Executor) ExecuteInteractive(command string) error {
+	command, secretEnv, err := e.resolveSecrets(command)
+	if err != nil {
+		return err
+	}
 	args := append(e.args, command)
 	cmd := exec.Command(e.binary, args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
-	cmd.Env = os.Environ()
+	cmd.Env = append(os.Environ(), secretEnv...)
 	if e.shellType == ShellBash && runtime.GOOS == "windows" {
 		cmd.Env = append(cmd.Env, "MSYS_NO_PATHCONV=1")
 	}
@@ -86,3 +96,16 @@ func ExecuteCD(path string) error {
 	}
 	return os.Chdir(path)
 }
+
+// ExitCode maps the error from running a command to its exit status: 0 on
+// success and 1 when the process could not be started at all.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return 1
+}
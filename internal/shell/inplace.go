@@ -0,0 +1,702 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Commands allowed to run against temporary copies for a preview. Anything
+// else in the line could have side effects outside the copied files. sed, awk
+// and perl scripts are checked by previewableSegment; perl only passes with
+// plain substitutions and transliterations.
+var previewSafeCommands = map[string]bool{
+	"sed": true, "awk": true, "gawk": true, "perl": true, "truncate": true,
+	"echo": true, "printf": true, "cat": true, "sort": true, "uniq": true,
+	"grep": true, "egrep": true, "head": true, "tail": true, "cut": true,
+	"tr": true, "jq": true, "column": true, "nl": true, "tac": true,
+	"rev": true, "paste": true, "fmt": true, "wc": true, "base64": true,
+	"iconv": true, "envsubst": true,
+}
+
+// previewDevices are the redirect targets a preview may write besides the
+// copies.
+var previewDevices = map[string]bool{"/dev/null": true, "/dev/stdout": true, "/dev/stderr": true}
+
+type InPlacePreview struct {
+	Command string
+	Dir     string
+	Files   []PreviewFile
+}
+
+type PreviewFile struct {
+	Path string
+	Copy string
+}
+
+// InPlaceTargets returns the existing files cmd would edit in place: sed -i,
+// perl -i, awk -i inplace, truncate, and > or >> redirects.
+func InPlaceTargets(cmd string) []string {
+	var paths []string
+	for _, idx := range inPlaceWords(tokenize(cmd)) {
+		paths = append(paths, idx.paths...)
+	}
+	return paths
+}
+
+type targetWord struct {
+	index int
+	paths []string
+}
+
+func inPlaceWords(tokens []word) []targetWord {
+	var out []targetWord
+	for _, i := range writeTargets(tokens) {
+		var paths []string
+		for _, p := range expandPath(tokens[i]) {
+			if info, err := os.Stat(p); err == nil && info.Mode().IsRegular() {
+				paths = append(paths, p)
+			}
+		}
+		if len(paths) > 0 {
+			out = append(out, targetWord{index: i, paths: paths})
+		}
+	}
+	return out
+}
+
+// writeTargets returns the indexes of the words naming files tokens write:
+// redirect targets and the files of in-place editors, whether or not they
+// exist.
+func writeTargets(tokens []word) []int {
+	var out []int
+	add := func(i int) {
+		out = append(out, i)
+	}
+
+	for _, sp := range segmentSpans(tokens) {
+		cmdIdx := -1
+		var args []int
+		for i := 0; i < len(sp.words); i++ {
+			w := sp.words[i]
+			if w.op {
+				i++
+				if i < len(sp.words) && redirectWrites(w, sp.words[i]) {
+					add(sp.start + i)
+				}
+				continue
+			}
+			if cmdIdx < 0 && !w.quoted && isAssignment(w.text) {
+				continue
+			}
+			if cmdIdx < 0 {
+				cmdIdx = i
+				continue
+			}
+			args = append(args, i)
+		}
+		if cmdIdx < 0 {
+			continue
+		}
+
+		words := make([]word, len(args))
+		for j, i := range args {
+			words[j] = sp.words[i]
+		}
+		for _, j := range inPlaceFileArgs(baseName(sp.words[cmdIdx].text), words) {
+			add(sp.start + args[j])
+		}
+	}
+	return out
+}
+
+// redirectWrites reports whether the redirect op writes to the file target.
+// >&N and >&- duplicate or close a descriptor, but >& file, like &> file,
+// sends both outputs to file.
+func redirectWrites(op, target word) bool {
+	if !strings.Contains(op.text, ">") {
+		return false
+	}
+	if strings.HasSuffix(op.text, "&") {
+		return target.quoted || !isDigits(target.text) && target.text != "-"
+	}
+	return true
+}
+
+// inPlaceFileArgs returns the indexes in args of the files an in-place editor
+// will rewrite, or nil when the command does not edit in place.
+func inPlaceFileArgs(name string, args []word) []int {
+	switch name {
+	case "sed":
+		if !hasShortOrLong(args, "i", "in-place") {
+			return nil
+		}
+		return operands(args, map[string]bool{"-e": true, "-f": true, "--expression": true, "--file": true, "-l": true},
+			!hasAnyFlag(args, "-e", "-f", "--expression", "--file"))
+	case "perl":
+		if !hasShortOrLong(args, "i", "") {
+			return nil
+		}
+		return operands(args, map[string]bool{"-e": true, "-E": true, "-I": true, "-M": true},
+			!hasShortOrLong(args, "e", "") && !hasShortOrLong(args, "E", ""))
+	case "awk", "gawk":
+		inplace := false
+		for j := 0; j+1 < len(args); j++ {
+			if args[j].text == "-i" && args[j+1].text == "inplace" {
+				inplace = true
+			}
+		}
+		if !inplace {
+			return nil
+		}
+		return operands(args, map[string]bool{"-i": true, "-f": true, "-v": true, "-F": true},
+			!hasAnyFlag(args, "-f"))
+	case "truncate":
+		return operands(args, map[string]bool{"-s": true, "-r": true, "--size": true, "--reference": true}, false)
+	}
+	return nil
+}
+
+// operands returns the indexes of non-flag arguments, skipping the values of
+// flags in valueFlags and, if skipScript is set, the first operand (the inline
+// program for sed, perl and awk).
+func operands(args []word, valueFlags map[string]bool, skipScript bool) []int {
+	var out []int
+	for j := 0; j < len(args); j++ {
+		a := args[j]
+		if !a.quoted && strings.HasPrefix(a.text, "-") && len(a.text) > 1 {
+			if valueFlags[a.text] {
+				j++
+			} else if len(a.text) > 2 && !strings.HasPrefix(a.text, "--") && valueFlags["-"+a.text[len(a.text)-1:]] {
+				// bundled short flags ending in one that takes a value, as in sed -ne
+				j++
+			}
+			continue
+		}
+		if skipScript {
+			skipScript = false
+			continue
+		}
+		out = append(out, j)
+	}
+	return out
+}
+
+func hasAnyFlag(args []word, flags ...string) bool {
+	for _, a := range args {
+		for _, f := range flags {
+			if !a.quoted && (a.text == f || strings.HasPrefix(a.text, f+"=")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasShortOrLong(args []word, short, long string) bool {
+	for _, a := range args {
+		s := a.text
+		if a.quoted || !strings.HasPrefix(s, "-") {
+			continue
+		}
+		if strings.HasPrefix(s, "--") {
+			if long != "" && (s == "--"+long || strings.HasPrefix(s, "--"+long+"=")) {
+				return true
+			}
+			continue
+		}
+		if strings.Contains(s[1:], short) {
+			return true
+		}
+	}
+	return false
+}
+
+// PrepareInPlacePreview copies the files cmd edits in place into a temporary
+// directory and rewrites cmd to operate on the copies. It returns nil when cmd
+// edits nothing in place, contains commands that are unsafe to run early, or
+// writes anything besides the copies.
+func PrepareInPlacePreview(st ShellType, cmd string) (*InPlacePreview, error) {
+	// command and process substitutions run whatever they contain
+	if strings.Contains(cmd, "$(") || strings.ContainsAny(cmd, "`") ||
+		strings.Contains(cmd, "<(") || strings.Contains(cmd, ">(") {
+		return nil, nil
+	}
+	tokens := tokenize(cmd)
+	targets := inPlaceWords(tokens)
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	for _, seg := range segmentSpans(tokens) {
+		if !previewableSegment(seg.words) {
+			return nil, nil
+		}
+	}
+	copied := make(map[int]bool)
+	for _, t := range targets {
+		copied[t.index] = true
+	}
+	for _, i := range writeTargets(tokens) {
+		if !copied[i] && !previewDevices[tokens[i].text] {
+			return nil, nil
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "nlcli-preview-")
+	if err != nil {
+		return nil, err
+	}
+	p := &InPlacePreview{Dir: dir}
+
+	copies := make(map[string]string)
+	replaced := make(map[int][]word)
+	for _, t := range targets {
+		for _, path := range t.paths {
+			dst, ok := copies[path]
+			if !ok {
+				dst = filepath.Join(dir, fmt.Sprintf("%d", len(copies)), filepath.Base(path))
+				if err := copyFile(path, dst); err != nil {
+					p.Cleanup()
+					return nil, err
+				}
+				copies[path] = dst
+				p.Files = append(p.Files, PreviewFile{Path: path, Copy: dst})
+			}
+			replaced[t.index] = append(replaced[t.index], word{text: dst, quoted: true})
+		}
+	}
+
+	var out []word
+	for i, w := range tokens {
+		if r, ok := replaced[i]; ok {
+			out = append(out, r...)
+			continue
+		}
+		// other references to an edited file, like the input of sort f > f,
+		// must see the copy too
+		if !w.op {
+			if paths := expandPath(w); len(paths) == 1 && copies[paths[0]] != "" {
+				out = append(out, word{text: copies[paths[0]], quoted: true})
+				continue
+			}
+		}
+		out = append(out, w)
+	}
+	p.Command = joinWords(st, out)
+	return p, nil
+}
+
+// previewableSegment reports whether seg only reads and edits files when run
+// against the copies: a command from previewSafeCommands without options
+// that write elsewhere, and for sed and awk a script that can neither run
+// programs nor write files. A wrapper such as sudo is not unwrapped, since it
+// would run with its privileges during the preview.
+func previewableSegment(seg []word) bool {
+	words := plainWords(seg)
+	if len(words) == 0 {
+		return true
+	}
+	name, args := baseName(words[0].text), words[1:]
+	if !previewSafeCommands[name] {
+		return false
+	}
+	switch name {
+	case "sed":
+		scripts, ok := sedScripts(args)
+		if !ok {
+			return false
+		}
+		for _, script := range scripts {
+			if !sedScriptSafe(script) {
+				return false
+			}
+		}
+	case "awk", "gawk":
+		scripts, ok := awkScripts(args)
+		if !ok {
+			return false
+		}
+		for _, script := range scripts {
+			if !awkScriptSafe(script) {
+				return false
+			}
+		}
+	case "perl":
+		scripts, ok := perlScripts(args)
+		if !ok {
+			return false
+		}
+		for _, script := range scripts {
+			if !perlScriptSafe(script) {
+				return false
+			}
+		}
+	case "sort":
+		// --compress-program runs any program on the temporary files
+		return !hasShortOrLong(args, "o", "") && !hasLongPrefix(args, "output", 1) && !hasLongPrefix(args, "compress-program", 2)
+	case "iconv", "base64":
+		return !hasShortOrLong(args, "o", "") && !hasLongPrefix(args, "output", 1)
+	case "uniq":
+		// uniq in out writes out
+		return len(operands(args, map[string]bool{"-f": true, "-s": true, "-w": true}, false)) <= 1
+	}
+	return true
+}
+
+// sedScripts returns the scripts of a sed command line. ok is false when a
+// script comes from a file.
+func sedScripts(args []word) (scripts []string, ok bool) {
+	var first string
+	hasFirst := false
+	for j := 0; j < len(args); j++ {
+		a := args[j].text
+		switch {
+		case args[j].quoted || !strings.HasPrefix(a, "-") || a == "-":
+			if !hasFirst {
+				first, hasFirst = a, true
+			}
+		case strings.HasPrefix(a, "--"):
+			name, value, hasValue := strings.Cut(a[2:], "=")
+			switch name {
+			case "expression":
+				if !hasValue {
+					if j++; j >= len(args) {
+						return nil, false
+					}
+					value = args[j].text
+				}
+				scripts = append(scripts, value)
+			case "file":
+				return nil, false
+			case "line-length":
+				if !hasValue {
+					j++
+				}
+			}
+		default:
+			for k := 1; k < len(a); k++ {
+				switch a[k] {
+				case 'f':
+					return nil, false
+				case 'e', 'l':
+					value := a[k+1:]
+					if value == "" {
+						if j++; j >= len(args) {
+							return nil, false
+						}
+						value = args[j].text
+					}
+					if a[k] == 'e' {
+						scripts = append(scripts, value)
+					}
+				case 'i':
+					// the rest is the backup suffix
+				default:
+					continue
+				}
+				break
+			}
+		}
+	}
+	if len(scripts) == 0 {
+		if !hasFirst {
+			return nil, false
+		}
+		scripts = []string{first}
+	}
+	return scripts, true
+}
+
+// sedScriptSafe reports whether a sed script only edits its input: it has
+// no w, W or e commands, no w or e flags on s, and nothing it does not
+// recognise.
+func sedScriptSafe(script string) bool {
+	toLineEnd := func(i int) int {
+		for i < len(script) && script[i] != '\n' {
+			i++
+		}
+		return i
+	}
+	for i := 0; i < len(script); {
+		c := script[i]
+		switch {
+		case strings.IndexByte(" \t\n;{}!,0123456789$~+", c) >= 0:
+			i++
+		case c == '#':
+			i = toLineEnd(i)
+		case c == '/':
+			if i = skipDelimited(script, i, '/'); i < 0 {
+				return false
+			}
+		case c == '\\':
+			if i+1 >= len(script) {
+				return false
+			}
+			if i = skipDelimited(script, i+1, script[i+1]); i < 0 {
+				return false
+			}
+		case c == 'I' || c == 'M':
+			// address modifiers
+			i++
+		case c == 's' || c == 'y':
+			if i+1 >= len(script) {
+				return false
+			}
+			d := script[i+1]
+			if i = skipDelimited(script, i+1, d); i < 0 {
+				return false
+			}
+			if i = skipDelimited(script, i-1, d); i < 0 {
+				return false
+			}
+			for ; i < len(script) && strings.IndexByte(";\n}", script[i]) < 0; i++ {
+				if c == 's' && (script[i] == 'w' || script[i] == 'e') {
+					return false
+				}
+			}
+		case c == 'a' || c == 'i' || c == 'c' || c == 'r' || c == 'R':
+			// text, or a file that is read into the output
+			i = toLineEnd(i)
+		case c == 'b' || c == 't' || c == 'T' || c == ':':
+			for i++; i < len(script) && script[i] != ';' && script[i] != '\n'; i++ {
+			}
+		case strings.IndexByte("pPdDnNgGhHxlLqQz=Fv", c) >= 0:
+			i++
+		default:
+			// w, W, e and anything unknown
+			return false
+		}
+	}
+	return true
+}
+
+// skipDelimited returns the index after the delimiter closing the text that
+// starts after s[start], or -1 when it is never closed.
+func skipDelimited(s string, start int, d byte) int {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case d:
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// awkScripts returns the programs of an awk command line. ok is false for a
+// program from a file and for options that load code.
+func awkScripts(args []word) (scripts []string, ok bool) {
+	var first string
+	hasFirst := false
+	for j := 0; j < len(args); j++ {
+		a := args[j].text
+		if args[j].quoted || !strings.HasPrefix(a, "-") || a == "-" {
+			if !hasFirst {
+				first, hasFirst = a, true
+			}
+			continue
+		}
+		switch {
+		case a == "-v" || a == "-F":
+			j++
+		case strings.HasPrefix(a, "-v") || strings.HasPrefix(a, "-F"):
+		case a == "-i" && j+1 < len(args) && args[j+1].text == "inplace":
+			j++
+		case a == "-e" || a == "--source":
+			if j++; j >= len(args) {
+				return nil, false
+			}
+			scripts = append(scripts, args[j].text)
+		case strings.HasPrefix(a, "--source="):
+			scripts = append(scripts, strings.TrimPrefix(a, "--source="))
+		case a == "--":
+		default:
+			// -f, -l, -E, other includes and anything unknown
+			return nil, false
+		}
+	}
+	if len(scripts) == 0 {
+		if !hasFirst {
+			return nil, false
+		}
+		scripts = []string{first}
+	}
+	return scripts, true
+}
+
+// awkScriptSafe reports whether an awk program can neither run programs nor
+// write files: no system, pipes, output redirection, getline, indirect calls
+// or @load. Comparisons with > are refused too, which only costs a preview.
+func awkScriptSafe(script string) bool {
+	if strings.ContainsAny(script, "|>@") {
+		return false
+	}
+	for _, name := range []string{"system", "getline", "close", "fflush"} {
+		if strings.Contains(script, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasLongPrefix reports whether args has the long option --long, or an
+// abbreviation of it at least min letters long, which getopt accepts too.
+func hasLongPrefix(args []word, long string, min int) bool {
+	for _, a := range args {
+		if a.quoted || !strings.HasPrefix(a.text, "--") {
+			continue
+		}
+		name, _, _ := strings.Cut(a.text[2:], "=")
+		if len(name) >= min && strings.HasPrefix(long, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// perlScripts returns the -e scripts of a perl command line. ok is false for
+// a script from a file and for any switch besides -p, -n, -i, -l, -a, -e and
+// -0, since -M, -m, -I and others load code.
+func perlScripts(args []word) (scripts []string, ok bool) {
+	for j := 0; j < len(args); j++ {
+		a := args[j].text
+		if args[j].quoted || !strings.HasPrefix(a, "-") || a == "-" {
+			continue
+		}
+		if a == "--" {
+			break
+		}
+		for k := 1; k < len(a); k++ {
+			switch a[k] {
+			case 'p', 'n', 'a':
+				continue
+			case 'i', '0', 'l':
+				// the rest is the backup suffix, record separator or line ending
+			case 'e':
+				value := a[k+1:]
+				if value == "" {
+					if j++; j >= len(args) {
+						return nil, false
+					}
+					value = args[j].text
+				}
+				scripts = append(scripts, value)
+			default:
+				return nil, false
+			}
+			break
+		}
+	}
+	return scripts, len(scripts) > 0
+}
+
+// perlScriptSafe reports whether a perl script is only s///, tr/// and y///
+// operations separated by semicolons. The e flag, code blocks in patterns and
+// interpolated expressions like ${\ ...} or @{[ ... ]} would run code, so
+// they are refused.
+func perlScriptSafe(script string) bool {
+	if strings.Contains(script, "(?{") || strings.Contains(script, "(??{") ||
+		strings.Contains(script, "${") || strings.Contains(script, "@{") {
+		return false
+	}
+	for i := 0; i < len(script); {
+		var flags string
+		switch {
+		case strings.IndexByte(" \t\n;", script[i]) >= 0:
+			i++
+			continue
+		case strings.HasPrefix(script[i:], "tr"):
+			flags, i = "cdsr", i+2
+		case script[i] == 'y':
+			flags, i = "cdsr", i+1
+		case script[i] == 's':
+			flags, i = "gimsxnpadlur", i+1
+		default:
+			return false
+		}
+		if i >= len(script) || isWordByte(script[i]) || strings.IndexByte(" \t\n;#=", script[i]) >= 0 {
+			return false
+		}
+		if _, paired := perlClosing[script[i]]; paired {
+			if i = skipPerlPart(script, i); i < 0 {
+				return false
+			}
+			for i < len(script) && strings.IndexByte(" \t\n", script[i]) >= 0 {
+				i++
+			}
+			if i >= len(script) {
+				return false
+			}
+			if i = skipPerlPart(script, i); i < 0 {
+				return false
+			}
+		} else {
+			if i = skipDelimited(script, i, script[i]); i < 0 {
+				return false
+			}
+			if i = skipDelimited(script, i-1, script[i-1]); i < 0 {
+				return false
+			}
+		}
+		for ; i < len(script) && isWordByte(script[i]); i++ {
+			if strings.IndexByte(flags, script[i]) < 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+var perlClosing = map[byte]byte{'{': '}', '(': ')', '[': ']', '<': '>'}
+
+// skipPerlPart returns the index after a bracketed part like {...} starting
+// at s[start], or -1 when it is never closed.
+func skipPerlPart(s string, start int) int {
+	open, end := s[start], perlClosing[s[start]]
+	depth := 1
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case open:
+			depth++
+		case end:
+			if depth--; depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func (p *InPlacePreview) Cleanup() {
+	os.RemoveAll(p.Dir)
+}
+
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,62 @@
+package shell
+
+import (
+	"strings"
+)
+
+// Commands that run what follows them as another (usually the root) user.
+var elevationCommands = map[string]bool{
+	"sudo": true, "doas": true, "su": true, "pkexec": true, "runas": true, "gsudo": true,
+}
+
+// Shells whose -c script is checked as a command of its own.
+var scriptShells = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "fish": true, "dash": true, "ksh": true,
+	"pwsh": true, "powershell": true,
+}
+
+// DetectElevation returns the privilege escalation mechanisms cmd uses:
+// sudo, doas, su, pkexec, runas and PowerShell's Start-Process -Verb RunAs.
+// Wrappers and shell -c scripts are looked through, so `env X=1 nohup sudo
+// ...` and `bash -c "sudo ..."` are found too.
+func DetectElevation(cmd string) []string {
+	var found []string
+	seen := make(map[string]bool)
+	detectElevation(cmd, func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			found = append(found, name)
+		}
+	})
+	return found
+}
+
+func detectElevation(cmd string, add func(string)) {
+	for _, seg := range splitCommand(cmd) {
+		words := plainWords(seg)
+		for len(words) > 0 {
+			name := baseName(words[0].text)
+			if elevationCommands[name] {
+				add(name)
+			}
+			if scriptShells[name] && hasFlag(words[1:], "c", "command") {
+				// the script, and to be safe any arguments after it
+				for _, a := range nonFlags(words[1:]) {
+					detectElevation(a.text, add)
+				}
+			}
+			next, ok := unwrapOnce(words)
+			if !ok {
+				break
+			}
+			words = next
+		}
+
+		for i, w := range seg {
+			t := strings.ToLower(w.text)
+			if t == "-verb:runas" || t == "-verb" && i+1 < len(seg) && strings.EqualFold(seg[i+1].text, "runas") {
+				add("runas")
+			}
+		}
+	}
+}
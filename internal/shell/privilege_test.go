@@ -0,0 +1,39 @@
+package shell
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDetectElevation(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want []string
+	}{
+		{cmd: "sudo rm -rf /var/cache/foo", want: []string{"sudo"}},
+		{cmd: "doas -u root pkg_add vim", want: []string{"doas"}},
+		{cmd: "su -c 'systemctl restart nginx'", want: []string{"su"}},
+		{cmd: "pkexec --user root apt-get update", want: []string{"pkexec"}},
+		{cmd: "env DEBUG=1 nohup sudo ./server", want: []string{"sudo"}},
+		{cmd: "ls && sudo -u postgres psql", want: []string{"sudo"}},
+		{cmd: "Start-Process pwsh -Verb RunAs", want: []string{"runas"}},
+		{cmd: "Start-Process notepad -Verb:RunAs", want: []string{"runas"}},
+		{cmd: `bash -c "sudo rm -rf /var/cache/foo"`, want: []string{"sudo"}},
+		{cmd: "sh -xc 'cd /tmp && doas reboot'", want: []string{"doas"}},
+		{cmd: `nohup bash -c "sh -c 'pkexec id'"`, want: []string{"pkexec"}},
+		{cmd: "pwsh -Command 'Start-Process notepad -Verb RunAs'", want: []string{"runas"}},
+		{cmd: "bash -c 'echo sudo'", want: nil},
+		{cmd: "bash sudo.sh", want: nil},
+		{cmd: "echo sudo", want: nil},
+		{cmd: "grep -r sudo /etc", want: nil},
+		{cmd: "ls -la", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cmd, func(t *testing.T) {
+			if got := DetectElevation(tt.cmd); !slices.Equal(got, tt.want) {
+				t.Errorf("DetectElevation(%q) = %v, want %v", tt.cmd, got, tt.want)
+			}
+		})
+	}
+}
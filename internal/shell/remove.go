@@ -0,0 +1,94 @@
+package shell
+
+import (
+	"os"
+	"strings"
+)
+
+var rmFlags = map[string]bool{
+	"r": true, "R": true, "f": true, "i": true, "I": true, "v": true, "d": true,
+	"recursive": true, "force": true, "verbose": true, "dir": true,
+}
+
+// SimpleRemoveTargets returns the paths removed by a plain rm or rmdir
+// invocation with no pipes, redirects or unusual flags. ok is false when the
+// command is anything more complex, or when the real command would fail (a
+// directory without -r, a missing path without -f), so it can run as is.
+func SimpleRemoveTargets(cmd string) (paths []string, ok bool) {
+	tokens := tokenize(cmd)
+	if len(tokens) < 2 {
+		return nil, false
+	}
+	for _, t := range tokens {
+		if t.op {
+			return nil, false
+		}
+	}
+
+	name := baseName(tokens[0].text)
+	if name != "rm" && name != "rmdir" {
+		return nil, false
+	}
+
+	recursive, force := false, false
+	var operands []word
+	endOfFlags := false
+	for _, t := range tokens[1:] {
+		s := t.text
+		switch {
+		case endOfFlags || !strings.HasPrefix(s, "-") || s == "-":
+			operands = append(operands, t)
+		case s == "--":
+			endOfFlags = true
+		case strings.HasPrefix(s, "--"):
+			flag := strings.TrimPrefix(s, "--")
+			if name == "rmdir" && flag != "verbose" || !rmFlags[flag] {
+				return nil, false
+			}
+			recursive = recursive || flag == "recursive"
+			force = force || flag == "force"
+		default:
+			for _, c := range s[1:] {
+				if name == "rmdir" && c != 'v' || !rmFlags[string(c)] {
+					return nil, false
+				}
+				recursive = recursive || c == 'r' || c == 'R'
+				force = force || c == 'f'
+			}
+		}
+	}
+	if len(operands) == 0 {
+		return nil, false
+	}
+
+	seen := make(map[string]bool)
+	for _, o := range operands {
+		for _, p := range expandPath(o) {
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+			info, err := os.Lstat(p)
+			if err != nil {
+				if force {
+					continue
+				}
+				return nil, false
+			}
+			if info.IsDir() {
+				if name == "rm" && !recursive {
+					return nil, false
+				}
+				if name == "rmdir" {
+					if entries, err := os.ReadDir(p); err != nil || len(entries) > 0 {
+						return nil, false
+					}
+				}
+			} else if name == "rmdir" {
+				return nil, false
+			}
+			paths = append(paths, p)
+		}
+	}
+	return paths, len(paths) > 0
+}
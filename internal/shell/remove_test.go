@@ -0,0 +1,57 @@
+package shell
+
+import (
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestSimpleRemoveTargets(t *testing.T) {
+	t.Chdir(t.TempDir())
+	for _, f := range []string{"a.txt", "b.txt", "c.log", "-x", "my file"} {
+		os.WriteFile(f, nil, 0644)
+	}
+	os.MkdirAll("build/obj", 0755)
+	os.Mkdir("empty", 0755)
+
+	tests := []struct {
+		cmd    string
+		want   []string
+		wantOK bool
+	}{
+		{cmd: "rm a.txt", want: []string{"a.txt"}, wantOK: true},
+		{cmd: "rm -rf build", want: []string{"build"}, wantOK: true},
+		{cmd: "rm -r -f build a.txt", want: []string{"build", "a.txt"}, wantOK: true},
+		{cmd: "rm --recursive --force build", want: []string{"build"}, wantOK: true},
+		{cmd: "rm build", wantOK: false},
+		{cmd: "rm -f missing a.txt", want: []string{"a.txt"}, wantOK: true},
+		{cmd: "rm missing", wantOK: false},
+		{cmd: "rm -- -x", want: []string{"-x"}, wantOK: true},
+		{cmd: "rm *.txt", want: []string{"a.txt", "b.txt"}, wantOK: true},
+		{cmd: "rm a.txt *.txt", want: []string{"a.txt", "b.txt"}, wantOK: true},
+		{cmd: "rm '*.txt'", wantOK: false},
+		{cmd: `rm "my file"`, want: []string{"my file"}, wantOK: true},
+		{cmd: `rm my\ file`, want: []string{"my file"}, wantOK: true},
+		// quotes do not stop rm from reading an option
+		{cmd: "rm '-x'", wantOK: false},
+		{cmd: "rm -f '-rx'", wantOK: false},
+		{cmd: "rm --one-file-system -rf build", wantOK: false},
+		{cmd: "rm -rfx build", wantOK: false},
+		{cmd: "rm -f", wantOK: false},
+		{cmd: "rmdir empty", want: []string{"empty"}, wantOK: true},
+		{cmd: "rmdir build", wantOK: false},
+		{cmd: "rmdir -p empty", wantOK: false},
+		{cmd: "rm a.txt && ls", wantOK: false},
+		{cmd: "rm a.txt > log", wantOK: false},
+		{cmd: "sudo rm a.txt", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cmd, func(t *testing.T) {
+			got, ok := SimpleRemoveTargets(tt.cmd)
+			if ok != tt.wantOK || !slices.Equal(got, tt.want) {
+				t.Errorf("SimpleRemoveTargets(%q) = %q, %v, want %q, %v", tt.cmd, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
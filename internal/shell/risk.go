@@ -0,0 +1,141 @@
+package shell
+
+import (
+	"strings"
+)
+
+type Risk int
+
+const (
+	RiskNone Risk = iota
+	RiskWrite
+	RiskHigh
+)
+
+func (r Risk) String() string {
+	switch r {
+	case RiskNone:
+		return "none"
+	case RiskWrite:
+		return "write"
+	case RiskHigh:
+		return "high"
+	default:
+		return "unknown"
+	}
+}
+
+var highRiskCommands = map[string]bool{
+	"dd": true, "mkfs": true, "format": true, "fdisk": true, "parted": true,
+	"wipefs": true, "truncate": true, "clear-content": true,
+}
+
+var writeCommands = map[string]bool{
+	"mkdir": true, "touch": true, "cp": true, "copy": true, "xcopy": true, "robocopy": true,
+	"ln": true, "tee": true, "install": true, "wget": true, "curl": true, "tar": true, "unzip": true,
+	"pip": true, "pip3": true, "npm": true, "yarn": true, "pnpm": true, "cargo": true,
+	"apt": true, "apt-get": true, "yum": true, "dnf": true, "brew": true, "pacman": true,
+	"ren": true, "rename": true, "new-item": true, "set-content": true, "add-content": true,
+	"copy-item": true, "out-file": true,
+}
+
+var writeGitCommands = map[string]bool{
+	"push": true, "commit": true, "merge": true, "rebase": true, "pull": true,
+	"checkout": true, "switch": true, "tag": true, "branch": true, "stash": true,
+}
+
+// AssessRisk rates cmd independently of the safety level. High-risk commands
+// destroy or overwrite existing data: deletes, moves, recursive permission
+// changes, find -delete/-exec, disk tools and in-place edits.
+func AssessRisk(cmd string) Risk {
+	risk := RiskNone
+	for _, seg := range splitCommand(cmd) {
+		for _, w := range seg {
+			if w.op && strings.Contains(w.text, ">") {
+				risk = max(risk, RiskWrite)
+			}
+		}
+
+		words := commandWords(seg)
+		if len(words) == 0 {
+			continue
+		}
+		name := baseName(words[0].text)
+		args := words[1:]
+
+		switch {
+		case removeCommands[name], moveCommands[name], highRiskCommands[name], strings.HasPrefix(name, "mkfs."):
+			return RiskHigh
+		case permCommands[name] && hasFlag(args, "R", "recursive"):
+			return RiskHigh
+		case name == "find" && hasAnyFlag(args, "-delete", "-exec", "-execdir"):
+			return RiskHigh
+		case name == "git" && len(args) > 0 && isDestructiveGit(args):
+			return RiskHigh
+		case inPlaceFileArgs(name, args) != nil:
+			return RiskHigh
+		case writeCommands[name], permCommands[name]:
+			risk = max(risk, RiskWrite)
+		case name == "git" && len(args) > 0 && writeGitCommands[args[0].text]:
+			risk = max(risk, RiskWrite)
+		}
+	}
+
+	if risk == RiskWrite && len(InPlaceTargets(cmd)) > 0 {
+		return RiskHigh
+	}
+	return risk
+}
+
+func isDestructiveGit(args []word) bool {
+	switch args[0].text {
+	case "clean":
+		return hasFlag(args[1:], "f", "force")
+	case "reset":
+		return hasFlag(args[1:], "hard")
+	case "checkout", "restore":
+		for _, a := range args[1:] {
+			if a.text == "--" || a.text == "." {
+				return true
+			}
+		}
+	case "stash":
+		return len(args) > 1 && (args[1].text == "drop" || args[1].text == "clear")
+	}
+	return false
+}
+
+// AffectedPaths lists the existing paths a command would delete, move over,
+// change permissions on or edit in place.
+func AffectedPaths(cmd string) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	add := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+
+	if br := ComputeBlastRadius(cmd); br != nil {
+		for _, t := range br.Targets {
+			if !t.Missing {
+				add(t.Path)
+			}
+		}
+	}
+	for _, p := range InPlaceTargets(cmd) {
+		add(p)
+	}
+	return paths
+}
+
+// ChangesPermissions reports whether cmd runs chmod, chown or chgrp.
+func ChangesPermissions(cmd string) bool {
+	for _, seg := range splitCommand(cmd) {
+		if words := commandWords(seg); len(words) > 0 && permCommands[baseName(words[0].text)] {
+			return true
+		}
+	}
+	return false
+}
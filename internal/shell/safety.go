@@ -1,6 +1,7 @@
 package shell
 
 import (
+	"strconv"
 	"strings"
 )
 
@@ -28,6 +29,17 @@ func (s SafetyLevel) String() string {
 	}
 }
 
+// ParseSafetyLevel accepts a level name (case-insensitive) or its number.
+func ParseSafetyLevel(s string) (SafetyLevel, bool) {
+	s = strings.TrimSpace(s)
+	for l := SafetyInstant; l <= SafetyStrict; l++ {
+		if strings.EqualFold(s, l.String()) || s == strconv.Itoa(int(l)) {
+			return l, true
+		}
+	}
+	return 0, false
+}
+
 func IsDangerous(cmd string, level SafetyLevel) bool {
 	if level == SafetyInstant {
 		return false
@@ -37,34 +49,38 @@ func IsDangerous(cmd string, level SafetyLevel) bool {
 	}
 
 	cmd = strings.ToLower(strings.TrimSpace(cmd))
-	parts := strings.Fields(cmd)
-	if len(parts) == 0 {
+
+	// judge each command by what actually runs, not by a sudo or env in front
+	var baseCmds []string
+	for _, seg := range splitCommand(cmd) {
+		if words := commandWords(seg); len(words) > 0 {
+			baseCmds = append(baseCmds, baseName(words[0].text))
+		}
+	}
+	if len(baseCmds) == 0 {
 		return false
 	}
 
-	baseCmd := parts[0]
-
 	destructive := []string{
 		"rm", "del", "rd", "rmdir",
 		"format", "mkfs", "fdisk", "shred",
 		"reset", "reboot", "shutdown",
 	}
 
-	for _, d := range destructive {
-		if baseCmd == d {
-			if baseCmd == "rm" && (strings.Contains(cmd, "-r") || strings.Contains(cmd, "-f")) {
-				return true
-			}
-			if level >= SafetyLax {
-				return true
+	for _, baseCmd := range baseCmds {
+		for _, d := range destructive {
+			if baseCmd == d {
+				if baseCmd == "rm" && (strings.Contains(cmd, "-r") || strings.Contains(cmd, "-f")) {
+					return true
+				}
+				if level >= SafetyLax {
+					return true
+				}
 			}
 		}
 	}
 
 	if level == SafetyLax {
-		if baseCmd == "rm" && (strings.Contains(cmd, "-rf") || strings.Contains(cmd, "/*")) {
-			return true
-		}
 		return false
 	}
 
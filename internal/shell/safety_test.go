@@ -16,6 +16,9 @@ func TestIsDangerous(t *testing.T) {
 		{name: "Lax - rm file", cmd: "rm file.txt", level: SafetyLax, expected: true},
 		{name: "Lax - rm recursive", cmd: "rm -r folder", level: SafetyLax, expected: true},
 		{name: "Lax - mkdir", cmd: "mkdir test", level: SafetyLax, expected: false},
+		{name: "Lax - sudo rm", cmd: "sudo rm file.txt", level: SafetyLax, expected: true},
+		{name: "Lax - sudo ls", cmd: "sudo ls /root", level: SafetyLax, expected: false},
+		{name: "Lax - rm after cd", cmd: "cd build && rm out.o", level: SafetyLax, expected: true},
 		{name: "Cautious - ls", cmd: "ls", level: SafetyCautious, expected: false},
 		{name: "Cautious - mkdir", cmd: "mkdir test", level: SafetyCautious, expected: true},
 		{name: "Cautious - touch", cmd: "touch file", level: SafetyCautious, expected: true},
@@ -34,3 +37,35 @@ func TestIsDangerous(t *testing.T) {
 		})
 	}
 }
+
+func TestAssessRisk(t *testing.T) {
+	tests := []struct {
+		cmd      string
+		expected Risk
+	}{
+		{cmd: "ls -la", expected: RiskNone},
+		{cmd: "cat file | grep foo", expected: RiskNone},
+		{cmd: "mkdir build", expected: RiskWrite},
+		{cmd: "echo hi > new.txt", expected: RiskWrite},
+		{cmd: "rm file.txt", expected: RiskHigh},
+		{cmd: "mv a.txt b.txt", expected: RiskHigh},
+		{cmd: "chmod -R 777 .", expected: RiskHigh},
+		{cmd: "chmod +x script.sh", expected: RiskWrite},
+		{cmd: "sudo -u root rm -rf /tmp/x", expected: RiskHigh},
+		{cmd: "env FOO=1 nohup rm build.log", expected: RiskHigh},
+		{cmd: "su -c 'rm -rf /var/tmp/x' root", expected: RiskHigh},
+		{cmd: "find . -name '*.tmp' -delete", expected: RiskHigh},
+		{cmd: "find . -name '*.tmp'", expected: RiskNone},
+		{cmd: "sed -i 's/a/b/' file", expected: RiskHigh},
+		{cmd: "git reset --hard HEAD~1", expected: RiskHigh},
+		{cmd: "git status", expected: RiskNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cmd, func(t *testing.T) {
+			if got := AssessRisk(tt.cmd); got != tt.expected {
+				t.Errorf("AssessRisk(%q) = %v, want %v", tt.cmd, got, tt.expected)
+			}
+		})
+	}
+}
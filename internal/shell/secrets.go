@@ -0,0 +1,121 @@
+package shell
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// secretRef matches {{secret:NAME}} references to the local vault.
+var secretRef = regexp.MustCompile(`\{\{secret:([A-Za-z_][A-Za-z0-9_]*)\}\}`)
+
+const secretEnvPrefix = "NLCLI_SECRET_"
+
+// SecretResolver returns the value of a stored secret.
+type SecretResolver func(name string) (string, error)
+
+// SecretRefs returns the distinct secret names cmd refers to.
+func SecretRefs(cmd string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, m := range secretRef.FindAllStringSubmatch(cmd, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// SetSecretResolver lets commands use {{secret:NAME}}. Without a resolver
+// such commands fail to run.
+func (e *Executor) SetSecretResolver(f SecretResolver) {
+	e.secrets = f
+}
+
+// resolveSecrets rewrites each {{secret:NAME}} into a reference to an
+// environment variable holding the value, so the secret never appears in the
+// command line passed to the shell. It returns the rewritten command and the
+// extra environment.
+func (e *Executor) resolveSecrets(cmd string) (string, []string, error) {
+	names := SecretRefs(cmd)
+	if len(names) == 0 {
+		return cmd, nil, nil
+	}
+	if e.secrets == nil {
+		return "", nil, fmt.Errorf("command uses {{secret:%s}} but no secret store is available", names[0])
+	}
+
+	var env []string
+	values := make(map[string]string)
+	for _, name := range names {
+		value, err := e.secrets(name)
+		if err != nil {
+			return "", nil, err
+		}
+		values[name] = value
+		env = append(env, secretEnvPrefix+name+"="+value)
+	}
+	return expandSecretRefs(e.shellType, cmd, values), env, nil
+}
+
+// expandSecretRefs replaces references with the shell's syntax for reading
+// the environment variable, keeping track of quoting so the value expands
+// and stays a single word. PowerShell cannot expand inside single quotes, so
+// there the value itself is inlined.
+func expandSecretRefs(st ShellType, cmd string, values map[string]string) string {
+	var sb strings.Builder
+	var quote byte
+	for i := 0; i < len(cmd); {
+		if loc := secretRef.FindStringSubmatchIndex(cmd[i:]); loc != nil && loc[0] == 0 && cmd[i] == '{' {
+			name := cmd[i+loc[2] : i+loc[3]]
+			if st == ShellPowerShell && quote == '\'' {
+				sb.WriteString(strings.ReplaceAll(values[name], "'", "''"))
+			} else {
+				sb.WriteString(secretExpansion(st, secretEnvPrefix+name, quote))
+			}
+			i += loc[1]
+			continue
+		}
+
+		c := cmd[i]
+		switch {
+		case quote == 0 && (c == '\'' || c == '"'):
+			quote = c
+		case quote != 0 && c == quote:
+			quote = 0
+		case c == '\\' && quote != '\'' && i+1 < len(cmd) && st != ShellPowerShell && st != ShellCmd:
+			sb.WriteByte(c)
+			i++
+			c = cmd[i]
+		}
+		sb.WriteByte(c)
+		i++
+	}
+	return sb.String()
+}
+
+func secretExpansion(st ShellType, v string, quote byte) string {
+	switch st {
+	case ShellCmd:
+		return "%" + v + "%"
+	case ShellPowerShell:
+		return "$env:" + v
+	case ShellFish:
+		switch quote {
+		case '\'':
+			return `'"$` + v + `"'`
+		case '"':
+			return "$" + v
+		}
+		return `"$` + v + `"`
+	default:
+		switch quote {
+		case '\'':
+			return `'"${` + v + `}"'`
+		case '"':
+			return "${" + v + "}"
+		}
+		return `"${` + v + `}"`
+	}
+}
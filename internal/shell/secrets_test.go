@@ -0,0 +1,49 @@
+package shell
+
+import (
+	"fmt"
+	"os/exec"
+	"testing"
+)
+
+func TestExecuteResolvesSecrets(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+
+	e := NewExecutor(ShellBash)
+	e.SetSecretResolver(func(name string) (string, error) {
+		if name == "TOKEN" {
+			return "s3cr3t 'quoted' $HOME", nil
+		}
+		return "", fmt.Errorf("no secret %s", name)
+	})
+
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{cmd: `printf '%s' {{secret:TOKEN}}`, want: `s3cr3t 'quoted' $HOME`},
+		{cmd: `printf '%s' "token={{secret:TOKEN}}"`, want: `token=s3cr3t 'quoted' $HOME`},
+		{cmd: `printf '%s' 'Authorization: {{secret:TOKEN}}'`, want: `Authorization: s3cr3t 'quoted' $HOME`},
+		{cmd: `printf '%s' \'{{secret:TOKEN}}`, want: `'s3cr3t 'quoted' $HOME`},
+	}
+
+	for _, tt := range tests {
+		stdout, stderr, err := e.Execute(tt.cmd)
+		if err != nil {
+			t.Errorf("Execute(%q) error = %v (%s)", tt.cmd, err, stderr)
+			continue
+		}
+		if stdout != tt.want {
+			t.Errorf("Execute(%q) = %q, want %q", tt.cmd, stdout, tt.want)
+		}
+	}
+
+	if _, _, err := e.Execute("echo {{secret:MISSING}}"); err == nil {
+		t.Error("Execute() with an unknown secret succeeded, want error")
+	}
+	if got := SecretRefs("a {{secret:A}} {{secret:B}} {{secret:A}} {{secret:bad-name}}"); len(got) != 2 {
+		t.Errorf("SecretRefs() = %v, want [A B]", got)
+	}
+}
@@ -0,0 +1,359 @@
+package shell
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	ThreatRemoteExec = "remote-exec"
+	ThreatExfil      = "exfiltration"
+	ThreatCredential = "credential-access"
+)
+
+type Threat struct {
+	Kind   string
+	Reason string
+}
+
+var downloaders = map[string]bool{
+	"curl": true, "wget": true, "fetch": true, "iwr": true, "irm": true,
+	"invoke-webrequest": true, "invoke-restmethod": true,
+}
+
+var decoders = map[string]bool{
+	"base64": true, "base32": true, "xxd": true, "openssl": true, "certutil": true,
+}
+
+var interpreters = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "fish": true, "dash": true, "ksh": true,
+	"python": true, "python3": true, "perl": true, "ruby": true, "node": true, "php": true,
+	"pwsh": true, "powershell": true, "iex": true, "invoke-expression": true, "source": true, ".": true,
+}
+
+var netcats = map[string]bool{
+	"nc": true, "ncat": true, "netcat": true, "socat": true, "telnet": true,
+}
+
+var remoteCopiers = map[string]bool{
+	"scp": true, "sftp": true, "rsync": true,
+}
+
+var substitutedDownload = regexp.MustCompile("(\\$\\(|<\\(|`)\\s*(curl|wget|iwr|irm|invoke-webrequest|invoke-restmethod)\\b")
+var psDownloadExec = regexp.MustCompile(`\b(iex|invoke-expression)\b.*\b(downloadstring|iwr|irm|invoke-webrequest|invoke-restmethod|net\.webclient)\b|\b(downloadstring|iwr|irm|invoke-webrequest|invoke-restmethod)\b.*\|\s*(iex|invoke-expression)\b`)
+
+var credentialPaths = []string{
+	"id_rsa", "id_dsa", "id_ecdsa", "id_ed25519",
+	".config/gcloud", ".kube/config", ".docker/config.json",
+	".gnupg", ".netrc", ".git-credentials", ".npmrc", ".pypirc",
+	"/etc/shadow", "/etc/sudoers", ".nlcli/.env", ".password-store",
+}
+
+// credentialDirs match as whole path components, so ~/.aws and "tar czf
+// k.tgz .ssh" count but .awsome does not.
+var credentialDirs = regexp.MustCompile(`(^|[^a-z0-9._-])(\.ssh|\.aws|\.azure)([^a-z0-9._-]|$)`)
+
+var knownHostsPath string
+
+func init() {
+	home, _ := os.UserHomeDir()
+	knownHostsPath = filepath.Join(home, ".ssh", "known_hosts")
+}
+
+// DetectThreats looks for the shapes of command a prompt-injected model would
+// produce: downloading and running code, decoding and running payloads,
+// sending local files to remote hosts and reading credential files.
+func DetectThreats(cmd string) []Threat {
+	var threats []Threat
+	lower := strings.ToLower(cmd)
+
+	if substitutedDownload.MatchString(lower) {
+		threats = append(threats, Threat{ThreatRemoteExec, "runs code downloaded through command substitution"})
+	}
+	if psDownloadExec.MatchString(lower) {
+		threats = append(threats, Threat{ThreatRemoteExec, "downloads a script and runs it with Invoke-Expression"})
+	}
+
+	tokens := tokenize(cmd)
+	var pipeline []string
+	piped := false
+	for _, sp := range segmentSpans(tokens) {
+		if sp.start == 0 || tokens[sp.start-1].text != "|" {
+			pipeline = nil
+			piped = false
+		} else {
+			piped = true
+		}
+
+		words := commandWords(sp.words)
+		if len(words) == 0 {
+			continue
+		}
+		name := baseName(words[0].text)
+		args := words[1:]
+
+		if piped && interpreters[name] && (!hasFlag(args, "c") || scriptReadsStdin(args)) {
+			for _, upstream := range pipeline {
+				switch {
+				case downloaders[upstream]:
+					threats = append(threats, Threat{ThreatRemoteExec, "pipes a download into " + name})
+				case decoders[upstream]:
+					threats = append(threats, Threat{ThreatRemoteExec, "pipes decoded data into " + name})
+				}
+			}
+		}
+		if name == "eval" || name == "iex" || name == "invoke-expression" {
+			for _, a := range args {
+				if strings.Contains(a.text, "$(") || strings.Contains(a.text, "`") {
+					threats = append(threats, Threat{ThreatRemoteExec, "evaluates the output of another command"})
+					break
+				}
+			}
+		}
+
+		if reason := uploadReason(name, args); reason != "" {
+			threats = append(threats, Threat{ThreatExfil, reason})
+		}
+		pipeline = append(pipeline, name)
+	}
+
+	normalized := strings.ReplaceAll(lower, "\\", "/")
+	if m := credentialDirs.FindStringSubmatch(normalized); m != nil {
+		threats = append(threats, Threat{ThreatCredential, "touches credential file " + m[2]})
+		return threats
+	}
+	for _, p := range credentialPaths {
+		if strings.Contains(normalized, p) {
+			threats = append(threats, Threat{ThreatCredential, "touches credential file " + strings.Trim(p, "/")})
+			break
+		}
+	}
+	return threats
+}
+
+// stdinFilters read stdin when they have no file operands (or only "-").
+var stdinFilters = map[string]bool{
+	"cat": true, "head": true, "tail": true, "source": true, ".": true,
+}
+
+var substitution = regexp.MustCompile("\\$\\(([^()]*)\\)|`([^`]*)`")
+
+// scriptReadsStdin reports whether the -c script of an interpreter takes
+// its input from stdin, as in curl ... | bash -c "$(cat)", so whatever is
+// piped into it still runs.
+func scriptReadsStdin(args []word) bool {
+	for _, a := range nonFlags(args) {
+		if readsStdin(a.text) {
+			return true
+		}
+	}
+	return false
+}
+
+func readsStdin(script string) bool {
+	lower := strings.ToLower(script)
+	if strings.Contains(lower, "stdin") || strings.Contains(lower, "/fd/0") {
+		return true
+	}
+	for _, m := range substitution.FindAllStringSubmatch(script, -1) {
+		if readsStdin(m[1] + m[2]) {
+			return true
+		}
+	}
+	tokens := tokenize(script)
+	for _, sp := range segmentSpans(tokens) {
+		// a command fed by a pipe inside the script does not see its stdin
+		if sp.start > 0 && tokens[sp.start-1].text == "|" {
+			continue
+		}
+		words := commandWords(sp.words)
+		if len(words) == 0 {
+			continue
+		}
+		name := baseName(words[0].text)
+		switch {
+		case name == "read" || name == "xargs" || name == "tee":
+			return true
+		case stdinFilters[name] || interpreters[name] && !hasFlag(words[1:], "c"):
+			operands := nonFlags(words[1:])
+			if len(operands) == 0 || operands[0].text == "-" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func uploadReason(name string, args []word) string {
+	switch {
+	case netcats[name]:
+		return name + " opens a raw network connection"
+	case name == "curl":
+		if curlUploads(args) {
+			return "curl uploads a local file"
+		}
+	case name == "wget":
+		if hasAnyFlag(args, "--post-file", "--body-file") {
+			return "wget uploads a local file"
+		}
+	case name == "invoke-webrequest" || name == "iwr" || name == "invoke-restmethod" || name == "irm":
+		for _, a := range args {
+			if strings.EqualFold(a.text, "-InFile") {
+				return name + " uploads a local file"
+			}
+		}
+	case remoteCopiers[name]:
+		operands := nonFlags(args)
+		if len(operands) < 2 {
+			return ""
+		}
+		dest := operands[len(operands)-1].text
+		host, ok := remoteHost(dest)
+		if ok && !isKnownHost(host) {
+			return name + " copies files to unknown host " + host
+		}
+	}
+	return ""
+}
+
+// curlUploadFlags are the curl options whose value can name a local file to
+// send, keyed by long name; the short forms are in curlShortUploads.
+var curlUploadFlags = map[string]bool{
+	"--form": true, "--data": true, "--data-ascii": true,
+	"--data-binary": true, "--data-urlencode": true, "--json": true, "--upload-file": true,
+}
+
+var curlShortUploads = map[byte]string{'F': "--form", 'd': "--data", 'T': "--upload-file"}
+
+// curlShortSwitches are short curl options without a value, which can come
+// before an upload option in a bundle like -sSd.
+const curlShortSwitches = "#0123456fgGiIjJkLlnNOpqRsSvV"
+
+// curlUploads reports whether a curl command sends a local file, whatever way
+// the option is spelled: -d @f, -d@f, -sd@f, -F name=@f, --data=@f.
+func curlUploads(args []word) bool {
+	sends := func(flag, value string) bool {
+		switch {
+		case flag == "--upload-file":
+			return true
+		case flag == "--form":
+			return strings.ContainsAny(value, "@<")
+		default:
+			return curlUploadFlags[flag] && strings.Contains(value, "@")
+		}
+	}
+
+	for i := 0; i < len(args); i++ {
+		a := args[i].text
+		next := ""
+		if i+1 < len(args) {
+			next = args[i+1].text
+		}
+		if args[i].quoted || !strings.HasPrefix(a, "-") {
+			continue
+		}
+		if strings.HasPrefix(a, "--") {
+			flag, value, attached := strings.Cut(a, "=")
+			if !attached {
+				value = next
+			}
+			if sends(flag, value) {
+				return true
+			}
+			continue
+		}
+		// short options can be bundled, with the one taking a value last
+		for j := 1; j < len(a); j++ {
+			flag, ok := curlShortUploads[a[j]]
+			if !ok {
+				if strings.IndexByte(curlShortSwitches, a[j]) < 0 {
+					break
+				}
+				continue
+			}
+			value := a[j+1:]
+			if value == "" {
+				value = next
+			}
+			if sends(flag, value) {
+				return true
+			}
+			break
+		}
+	}
+	return false
+}
+
+// remoteHost extracts the host from an scp/rsync destination like
+// user@host:path. Windows drive letters are not hosts.
+func remoteHost(dest string) (string, bool) {
+	if strings.HasPrefix(dest, "rsync://") {
+		dest = strings.TrimPrefix(dest, "rsync://")
+		host, _, _ := strings.Cut(dest, "/")
+		return host, host != ""
+	}
+	colon := strings.Index(dest, ":")
+	if colon <= 1 || strings.ContainsAny(dest[:colon], "/\\") {
+		return "", false
+	}
+	host := dest[:colon]
+	if at := strings.LastIndex(host, "@"); at >= 0 {
+		host = host[at+1:]
+	}
+	return strings.Trim(host, "[]"), host != ""
+}
+
+// isKnownHost checks ~/.ssh/known_hosts, including hashed entries.
+func isKnownHost(host string) bool {
+	f, err := os.Open(knownHostsPath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if strings.HasPrefix(fields[0], "@") && len(fields) > 1 {
+			fields = fields[1:]
+		}
+		for _, pattern := range strings.Split(fields[0], ",") {
+			if matchKnownHost(pattern, host) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchKnownHost(pattern, host string) bool {
+	if strings.HasPrefix(pattern, "|1|") {
+		parts := strings.Split(pattern, "|")
+		if len(parts) != 4 {
+			return false
+		}
+		salt, err1 := base64.StdEncoding.DecodeString(parts[2])
+		want, err2 := base64.StdEncoding.DecodeString(parts[3])
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		mac := hmac.New(sha1.New, salt)
+		mac.Write([]byte(host))
+		return hmac.Equal(mac.Sum(nil), want)
+	}
+	pattern = strings.TrimPrefix(pattern, "[")
+	if i := strings.Index(pattern, "]:"); i >= 0 {
+		pattern = pattern[:i]
+	}
+	ok, _ := filepath.Match(pattern, host)
+	return ok
+}
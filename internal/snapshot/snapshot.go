@@ -0,0 +1,480 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const MaxSize = 512 << 20
+
+var snapshotDir string
+var indexPath string
+
+func init() {
+	home, _ := os.UserHomeDir()
+	snapshotDir = filepath.Join(home, ".nlcli", "snapshots")
+	indexPath = filepath.Join(snapshotDir, "index.json")
+}
+
+// GitRef records a `git stash create` commit holding the tracked state of
+// Paths inside Repo.
+type GitRef struct {
+	Repo  string   `json:"repo"`
+	Ref   string   `json:"ref"`
+	Paths []string `json:"paths"`
+}
+
+type Snapshot struct {
+	ID      string    `json:"id"`
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	Paths   []string  `json:"paths"`
+	Archive string    `json:"archive,omitempty"`
+	Git     []GitRef  `json:"git,omitempty"`
+	Size    int64     `json:"size"`
+}
+
+func load() ([]Snapshot, error) {
+	data, err := os.ReadFile(indexPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []Snapshot
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return nil, fmt.Errorf("corrupt snapshot index: %w", err)
+	}
+	return snapshots, nil
+}
+
+func save(snapshots []Snapshot) error {
+	data, err := json.MarshalIndent(snapshots, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := indexPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, indexPath)
+}
+
+// Take snapshots paths before command runs. Tracked files inside git work
+// trees are captured with `git stash create`; everything else goes into a
+// gzipped tarball. Git only tracks contents and the executable bit, so
+// metadata forces the tarball when permissions or ownership must come back.
+func Take(command string, paths []string, metadata bool) (*Snapshot, error) {
+	snapshots, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(snapshotDir, 0700); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	snap := Snapshot{ID: strconv.Itoa(nextID(snapshots)), Time: now, Command: command}
+
+	var archived []string
+	repos := make(map[string][]string)
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, err
+		}
+		snap.Paths = append(snap.Paths, abs)
+		// deleting the work tree itself would take the stash commit with it
+		if repo := gitRoot(abs); !metadata && repo != "" && repo != abs {
+			repos[repo] = append(repos[repo], abs)
+			continue
+		}
+		archived = append(archived, abs)
+	}
+
+	for repo, repoPaths := range repos {
+		ref, tracked, untracked, err := gitSnapshot(repo, repoPaths)
+		if err != nil {
+			archived = append(archived, repoPaths...)
+			continue
+		}
+		if len(tracked) > 0 {
+			snap.Git = append(snap.Git, GitRef{Repo: repo, Ref: ref, Paths: tracked})
+		}
+		archived = append(archived, untracked...)
+	}
+
+	if len(archived) > 0 {
+		size, err := archiveSize(archived)
+		if err != nil {
+			return nil, err
+		}
+		if size > MaxSize {
+			return nil, fmt.Errorf("affected files total %d MB, over the %d MB snapshot limit", size>>20, MaxSize>>20)
+		}
+		snap.Archive = snap.ID + ".tar.gz"
+		if snap.Size, err = writeArchive(filepath.Join(snapshotDir, snap.Archive), archived); err != nil {
+			os.Remove(filepath.Join(snapshotDir, snap.Archive))
+			return nil, err
+		}
+	}
+
+	if err := save(append(snapshots, snap)); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+func nextID(snapshots []Snapshot) int {
+	id := 1
+	for _, s := range snapshots {
+		if n, err := strconv.Atoi(s.ID); err == nil && n >= id {
+			id = n + 1
+		}
+	}
+	return id
+}
+
+func List() ([]Snapshot, error) {
+	return load()
+}
+
+// Restore puts the files recorded in snapshot id back in place, overwriting
+// whatever is there now. Files created since the snapshot are left alone.
+// Archive entries outside the snapshot's Paths, or reached through a symlink,
+// are refused before anything is written.
+func Restore(id string) (*Snapshot, error) {
+	snapshots, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range snapshots {
+		if s.ID != id {
+			continue
+		}
+		for _, g := range s.Git {
+			args := append([]string{"-C", g.Repo, "checkout", g.Ref, "--"}, g.Paths...)
+			if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+				return nil, fmt.Errorf("git checkout: %s", strings.TrimSpace(string(out)))
+			}
+		}
+		if s.Archive != "" {
+			archive := filepath.Join(snapshotDir, s.Archive)
+			if err := checkArchive(archive, s.Paths); err != nil {
+				return nil, err
+			}
+			if err := extractArchive(archive, s.Paths); err != nil {
+				return nil, err
+			}
+		}
+		return &s, nil
+	}
+	return nil, fmt.Errorf("no snapshot %s", id)
+}
+
+// Purge deletes snapshot id, or every snapshot when id is empty, along with
+// its archive and the git refs that keep its stash commits alive.
+func Purge(id string) (int, error) {
+	snapshots, err := load()
+	if err != nil {
+		return 0, err
+	}
+
+	var kept []Snapshot
+	var refs []GitRef
+	purged := 0
+	for _, s := range snapshots {
+		if id != "" && s.ID != id {
+			kept = append(kept, s)
+			continue
+		}
+		if s.Archive != "" {
+			if err := os.Remove(filepath.Join(snapshotDir, s.Archive)); err != nil && !os.IsNotExist(err) {
+				return purged, err
+			}
+		}
+		refs = append(refs, s.Git...)
+		purged++
+	}
+	if id != "" && purged == 0 {
+		return 0, fmt.Errorf("no snapshot %s", id)
+	}
+	if err := save(kept); err != nil {
+		return purged, err
+	}
+
+	// snapshots taken with no changes in between share a stash commit
+	used := make(map[string]bool)
+	for _, s := range kept {
+		for _, g := range s.Git {
+			used[g.Repo+"\x00"+g.Ref] = true
+		}
+	}
+	for _, g := range refs {
+		if !used[g.Repo+"\x00"+g.Ref] {
+			exec.Command("git", "-C", g.Repo, "update-ref", "-d", "refs/nlcli/snapshots/"+g.Ref).Run()
+		}
+	}
+	return purged, nil
+}
+
+func gitRoot(path string) string {
+	dir := path
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return ""
+	}
+	return filepath.Clean(strings.TrimSpace(string(out)))
+}
+
+// gitSnapshot records the tracked state of the work tree as a dangling stash
+// commit (or HEAD when there are no local changes). It splits paths into those
+// git can restore and the untracked files among them, which it cannot.
+func gitSnapshot(repo string, paths []string) (ref string, tracked, untracked []string, err error) {
+	out, err := exec.Command("git", "-C", repo, "stash", "create").Output()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	ref = strings.TrimSpace(string(out))
+	if ref == "" {
+		out, err := exec.Command("git", "-C", repo, "rev-parse", "HEAD").Output()
+		if err != nil {
+			return "", nil, nil, err
+		}
+		ref = strings.TrimSpace(string(out))
+	}
+	// keep the commit reachable so gc does not collect it
+	exec.Command("git", "-C", repo, "update-ref", "refs/nlcli/snapshots/"+ref, ref).Run()
+
+	for _, p := range paths {
+		if exec.Command("git", "-C", repo, "ls-files", "--error-unmatch", "--", p).Run() == nil {
+			tracked = append(tracked, p)
+		}
+	}
+
+	args := append([]string{"-C", repo, "ls-files", "--others", "-z", "--"}, paths...)
+	out, err = exec.Command("git", args...).Output()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	for _, f := range bytes.Split(out, []byte{0}) {
+		if len(f) > 0 {
+			untracked = append(untracked, filepath.Join(repo, filepath.FromSlash(string(f))))
+		}
+	}
+	return ref, tracked, untracked, nil
+}
+
+func archiveSize(paths []string) (int64, error) {
+	var total int64
+	for _, p := range paths {
+		err := filepath.Walk(p, func(_ string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if info.Mode().IsRegular() {
+				total += info.Size()
+			}
+			if total > MaxSize {
+				return filepath.SkipAll
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+func writeArchive(dst string, paths []string) (int64, error) {
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	for _, root := range paths {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			link := ""
+			if info.Mode()&os.ModeSymlink != 0 {
+				link, _ = os.Readlink(path)
+			}
+			hdr, err := tar.FileInfoHeader(info, link)
+			if err != nil {
+				return err
+			}
+			hdr.Name = filepath.ToSlash(path)
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			in, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer in.Close()
+			_, err = io.Copy(tw, in)
+			return err
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// contained reports whether path is one of roots or lies beneath one, and
+// returns that root.
+func contained(path string, roots []string) (string, bool) {
+	if !filepath.IsAbs(path) || filepath.Clean(path) != path {
+		return "", false
+	}
+	for _, root := range roots {
+		if path == root || strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator)) {
+			return root, true
+		}
+	}
+	return "", false
+}
+
+// checkArchive makes sure every entry of the archive at src falls under the
+// snapshot's recorded paths.
+func checkArchive(src string, roots []string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok := contained(filepath.FromSlash(hdr.Name), roots); !ok {
+			return fmt.Errorf("snapshot archive entry %s is outside the snapshot's paths", hdr.Name)
+		}
+	}
+}
+
+// throughSymlink reports whether any directory between root and path,
+// including root itself, is currently a symlink, so writing path would land
+// somewhere else.
+func throughSymlink(root, path string) bool {
+	for dir := filepath.Dir(path); len(dir) >= len(root); dir = filepath.Dir(dir) {
+		if info, err := os.Lstat(dir); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return true
+		}
+		if dir == root {
+			break
+		}
+	}
+	return false
+}
+
+func extractArchive(src string, roots []string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+
+	var dirs []*tar.Header
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		path := filepath.FromSlash(hdr.Name)
+		mode := os.FileMode(hdr.Mode).Perm()
+		root, ok := contained(path, roots)
+		if !ok || throughSymlink(root, path) {
+			return fmt.Errorf("snapshot archive entry %s is outside the snapshot's paths", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0700); err != nil {
+				return err
+			}
+			dirs = append(dirs, hdr)
+		case tar.TypeSymlink:
+			os.Remove(path)
+			if err := os.Symlink(hdr.Linkname, path); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			os.MkdirAll(filepath.Dir(path), 0755)
+			out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return err
+			}
+			out.Close()
+			os.Lchown(path, hdr.Uid, hdr.Gid)
+			os.Chmod(path, mode)
+		}
+	}
+
+	// directory modes last, so restrictive ones do not block writing their contents
+	for _, hdr := range dirs {
+		os.Lchown(filepath.FromSlash(hdr.Name), hdr.Uid, hdr.Gid)
+		os.Chmod(filepath.FromSlash(hdr.Name), os.FileMode(hdr.Mode).Perm())
+	}
+	return nil
+}
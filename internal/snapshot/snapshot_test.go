@@ -0,0 +1,49 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestContained(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "home", "u", "proj")
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: root, want: true},
+		{path: filepath.Join(root, "a.txt"), want: true},
+		{path: filepath.Join(root, "sub", "b.txt"), want: true},
+		{path: root + "2", want: false},
+		{path: filepath.Join(string(filepath.Separator), "etc", "passwd"), want: false},
+		{path: root + string(filepath.Separator) + ".." + string(filepath.Separator) + "x", want: false},
+		{path: "proj/a.txt", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if _, got := contained(tt.path, []string{root}); got != tt.want {
+				t.Errorf("contained(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThroughSymlink(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "dir"), 0755)
+	if err := os.Symlink(t.TempDir(), filepath.Join(root, "link")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	if throughSymlink(root, filepath.Join(root, "dir", "a.txt")) {
+		t.Error("plain directory reported as a symlink")
+	}
+	if !throughSymlink(root, filepath.Join(root, "link", "a.txt")) {
+		t.Error("path through a symlink not detected")
+	}
+	if throughSymlink(root, filepath.Join(root, "link")) {
+		t.Error("the symlink itself is restored in place, not followed")
+	}
+}
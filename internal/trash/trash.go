@@ -0,0 +1,247 @@
+package trash
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var trashDir string
+var manifestPath string
+
+func init() {
+	home, _ := os.UserHomeDir()
+	trashDir = filepath.Join(home, ".nlcli", "trash")
+	manifestPath = filepath.Join(trashDir, "manifest.json")
+}
+
+type Item struct {
+	Original string `json:"original"`
+	Stored   string `json:"stored"`
+}
+
+type Entry struct {
+	ID      string    `json:"id"`
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	Items   []Item    `json:"items"`
+}
+
+func load() ([]Entry, error) {
+	data, err := os.ReadFile(manifestPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("corrupt trash manifest: %w", err)
+	}
+	return entries, nil
+}
+
+func save(entries []Entry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := manifestPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, manifestPath)
+}
+
+// Move puts paths into a new trash entry, recording where each came from.
+// Paths moved before a failure stay in the trash and are recorded.
+func Move(command string, paths []string) (*Entry, error) {
+	entries, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	entry := Entry{ID: now.Format("20060102-150405"), Time: now, Command: command}
+	for n := 2; exists(entries, entry.ID); n++ {
+		entry.ID = now.Format("20060102-150405") + "-" + strconv.Itoa(n)
+	}
+
+	entryDir := filepath.Join(trashDir, entry.ID)
+	if err := os.MkdirAll(entryDir, 0700); err != nil {
+		return nil, err
+	}
+
+	var moveErr error
+	for i, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			moveErr = err
+			break
+		}
+		if rel, err := filepath.Rel(abs, trashDir); err == nil && !strings.HasPrefix(rel, "..") {
+			moveErr = fmt.Errorf("cannot move %s into the trash inside it", abs)
+			break
+		}
+		stored := filepath.Join(entry.ID, fmt.Sprintf("%d-%s", i, filepath.Base(abs)))
+		if err := move(abs, filepath.Join(trashDir, stored)); err != nil {
+			moveErr = err
+			break
+		}
+		entry.Items = append(entry.Items, Item{Original: abs, Stored: stored})
+	}
+
+	if len(entry.Items) == 0 {
+		os.Remove(entryDir)
+		return nil, moveErr
+	}
+	if err := save(append(entries, entry)); err != nil {
+		return nil, err
+	}
+	return &entry, moveErr
+}
+
+// Undo restores the most recent entry. Items whose original path has been
+// reused are left in the trash and reported.
+func Undo() (*Entry, error) {
+	entries, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("trash is empty")
+	}
+
+	entry := entries[len(entries)-1]
+	var remaining []Item
+	var restoreErr error
+	for _, item := range entry.Items {
+		if _, err := os.Lstat(item.Original); err == nil {
+			remaining = append(remaining, item)
+			restoreErr = fmt.Errorf("%s already exists", item.Original)
+			continue
+		}
+		os.MkdirAll(filepath.Dir(item.Original), 0755)
+		if err := move(filepath.Join(trashDir, item.Stored), item.Original); err != nil {
+			remaining = append(remaining, item)
+			restoreErr = err
+		}
+	}
+
+	if len(remaining) > 0 {
+		entries[len(entries)-1].Items = remaining
+	} else {
+		entries = entries[:len(entries)-1]
+		os.RemoveAll(filepath.Join(trashDir, entry.ID))
+	}
+	if err := save(entries); err != nil {
+		return nil, err
+	}
+	return &entry, restoreErr
+}
+
+func List() ([]Entry, error) {
+	return load()
+}
+
+// Purge permanently deletes the entry with the given id, or everything when id
+// is empty.
+func Purge(id string) (int, error) {
+	entries, err := load()
+	if err != nil {
+		return 0, err
+	}
+
+	var kept []Entry
+	purged := 0
+	for _, e := range entries {
+		if id != "" && e.ID != id {
+			kept = append(kept, e)
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(trashDir, e.ID)); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	if id != "" && purged == 0 {
+		return 0, fmt.Errorf("no trash entry %s", id)
+	}
+	return purged, save(kept)
+}
+
+func (e Entry) Size() int64 {
+	var total int64
+	for _, item := range e.Items {
+		filepath.Walk(filepath.Join(trashDir, item.Stored), func(_ string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				total += info.Size()
+			}
+			return nil
+		})
+	}
+	return total
+}
+
+func exists(entries []Entry, id string) bool {
+	for _, e := range entries {
+		if e.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// move renames src to dst, falling back to copy and delete across devices.
+func move(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	if err := copyTree(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+func copyTree(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			in, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer in.Close()
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, in); err != nil {
+				out.Close()
+				return err
+			}
+			return out.Close()
+		}
+	})
+}
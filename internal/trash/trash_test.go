@@ -0,0 +1,87 @@
+package trash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMoveUndoPurge(t *testing.T) {
+	dir := t.TempDir()
+	trashDir = filepath.Join(dir, "trash")
+	manifestPath = filepath.Join(trashDir, "manifest.json")
+
+	file := filepath.Join(dir, "notes.txt")
+	tree := filepath.Join(dir, "build")
+	os.WriteFile(file, []byte("keep me"), 0644)
+	os.MkdirAll(filepath.Join(tree, "obj"), 0755)
+	os.WriteFile(filepath.Join(tree, "obj", "main.o"), []byte("obj"), 0644)
+
+	entry, err := Move("rm -rf notes.txt build", []string{file, tree})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entry.Items) != 2 {
+		t.Fatalf("Move() stored %d items, want 2", len(entry.Items))
+	}
+	for _, p := range []string{file, tree} {
+		if _, err := os.Lstat(p); !os.IsNotExist(err) {
+			t.Errorf("%s still exists after Move()", p)
+		}
+	}
+	if size := entry.Size(); size != int64(len("keep me")+len("obj")) {
+		t.Errorf("Size() = %d", size)
+	}
+	if _, err := Move("rm trash", []string{trashDir}); err == nil {
+		t.Error("Move() accepted the trash directory itself")
+	}
+
+	if _, err := Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(file); err != nil || string(data) != "keep me" {
+		t.Errorf("restored %s = %q, %v", file, data, err)
+	}
+	if _, err := os.Stat(filepath.Join(tree, "obj", "main.o")); err != nil {
+		t.Errorf("restored tree is incomplete: %v", err)
+	}
+	if entries, _ := List(); len(entries) != 0 {
+		t.Errorf("List() after Undo() = %v, want empty", entries)
+	}
+	if _, err := Undo(); err == nil {
+		t.Error("Undo() of an empty trash succeeded")
+	}
+
+	// a restore never overwrites a path that has been reused
+	if _, err := Move("rm notes.txt", []string{file}); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(file, []byte("new"), 0644)
+	if _, err := Undo(); err == nil {
+		t.Error("Undo() over an existing file succeeded")
+	}
+	if data, _ := os.ReadFile(file); string(data) != "new" {
+		t.Errorf("Undo() overwrote %s", file)
+	}
+
+	os.Remove(file)
+	second, err := Move("rm build", []string{tree})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Purge("no-such-entry"); err == nil {
+		t.Error("Purge() of an unknown id succeeded")
+	}
+	if n, err := Purge(second.ID); err != nil || n != 1 {
+		t.Errorf("Purge(%s) = %d, %v, want 1", second.ID, n, err)
+	}
+	if _, err := os.Stat(filepath.Join(trashDir, second.ID)); !os.IsNotExist(err) {
+		t.Errorf("purged entry %s is still on disk", second.ID)
+	}
+	if n, err := Purge(""); err != nil || n != 1 {
+		t.Errorf("Purge(\"\") = %d, %v, want 1", n, err)
+	}
+	if entries, _ := List(); len(entries) != 0 {
+		t.Errorf("List() after Purge() = %v, want empty", entries)
+	}
+}
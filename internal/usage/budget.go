@@ -0,0 +1,108 @@
+package usage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Action is what happens once a budget is exceeded.
+type Action int
+
+const (
+	ActionWarn Action = iota
+	// ActionDowngrade moves requests to a cheaper model. Rate limits are
+	// refused instead, since a cheaper model does not slow anything down.
+	ActionDowngrade
+	ActionRefuse
+)
+
+func (a Action) String() string {
+	switch a {
+	case ActionDowngrade:
+		return "downgrade"
+	case ActionRefuse:
+		return "refuse"
+	default:
+		return "warn"
+	}
+}
+
+func ParseAction(s string) (Action, bool) {
+	switch strings.ToLower(s) {
+	case "warn":
+		return ActionWarn, true
+	case "downgrade":
+		return ActionDowngrade, true
+	case "refuse":
+		return ActionRefuse, true
+	}
+	return ActionWarn, false
+}
+
+// Budget caps spending across all sessions. Zero fields are not limited.
+type Budget struct {
+	Path              string
+	DailyCost         float64
+	MonthlyCost       float64
+	DailyTokens       int64
+	MonthlyTokens     int64
+	RequestsPerMinute int
+	Action            Action
+	// CheapModel is what ActionDowngrade switches to; by default the
+	// cheapest priced model of the same provider.
+	CheapModel string
+}
+
+// Check returns why b is exceeded, or "" when it is not. rateLimited is set
+// when the reason is the request rate.
+func (l *Ledger) Check(b Budget) (reason string, rateLimited bool, err error) {
+	f, err := readFile()
+	if err != nil {
+		return "", false, err
+	}
+	now := l.now()
+	if b.RequestsPerMinute > 0 {
+		if n := recentRequests(f.Recent, now); n >= b.RequestsPerMinute {
+			return fmt.Sprintf("%d requests in the last minute (limit %d)", n, b.RequestsPerMinute), true, nil
+		}
+	}
+
+	var day, month Totals
+	today, thisMonth := now.Format(dateFormat), now.Format("2006-01")
+	for date, rows := range f.Days {
+		if strings.HasPrefix(date, thisMonth) {
+			month.add(rows.Sum())
+		}
+		if date == today {
+			day.add(rows.Sum())
+		}
+	}
+
+	switch {
+	case b.DailyCost > 0 && day.Cost >= b.DailyCost:
+		return fmt.Sprintf("$%.2f spent today (limit $%.2f)", day.Cost, b.DailyCost), false, nil
+	case b.MonthlyCost > 0 && month.Cost >= b.MonthlyCost:
+		return fmt.Sprintf("$%.2f spent this month (limit $%.2f)", month.Cost, b.MonthlyCost), false, nil
+	case b.DailyTokens > 0 && day.InputTokens+day.OutputTokens >= b.DailyTokens:
+		return fmt.Sprintf("%d tokens used today (limit %d)", day.InputTokens+day.OutputTokens, b.DailyTokens), false, nil
+	case b.MonthlyTokens > 0 && month.InputTokens+month.OutputTokens >= b.MonthlyTokens:
+		return fmt.Sprintf("%d tokens used this month (limit %d)", month.InputTokens+month.OutputTokens, b.MonthlyTokens), false, nil
+	}
+	return "", false, nil
+}
+
+// Price returns the price of model, and whether one is known.
+func (l *Ledger) Price(provider, model string) (Price, bool) {
+	return lookup(l.prices, provider, model)
+}
+
+func recentRequests(recent []time.Time, now time.Time) int {
+	n := 0
+	for _, t := range recent {
+		if now.Sub(t) < time.Minute {
+			n++
+		}
+	}
+	return n
+}
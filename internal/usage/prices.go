@@ -0,0 +1,65 @@
+package usage
+
+import "strings"
+
+// Price is what a model costs in USD per million tokens.
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// DefaultPrices are list prices at the time of writing. Override or extend
+// them in ~/.nlcli/prices.toml.
+var DefaultPrices = map[string]Price{
+	"gpt-4o":                  {2.50, 10.00},
+	"gpt-4o-mini":             {0.15, 0.60},
+	"gpt-4-turbo":             {10.00, 30.00},
+	"gpt-3.5-turbo":           {0.50, 1.50},
+	"gpt-4.1":                 {2.00, 8.00},
+	"gpt-4.1-mini":            {0.40, 1.60},
+	"gpt-4.1-nano":            {0.10, 0.40},
+	"o3-mini":                 {1.10, 4.40},
+	"o4-mini":                 {1.10, 4.40},
+	"claude-opus-4":           {15.00, 75.00},
+	"claude-sonnet-4":         {3.00, 15.00},
+	"claude-3-5-haiku":        {0.80, 4.00},
+	"claude-3-haiku":          {0.25, 1.25},
+	"gemini-2.5-pro":          {1.25, 10.00},
+	"gemini-2.5-flash":        {0.30, 2.50},
+	"gemini-2.0-flash":        {0.10, 0.40},
+	"gemini-1.5-pro":          {1.25, 5.00},
+	"gemini-1.5-flash":        {0.075, 0.30},
+	"llama-3.3-70b-versatile": {0.59, 0.79},
+	"llama-3.1-8b-instant":    {0.05, 0.08},
+	"mixtral-8x7b-32768":      {0.24, 0.24},
+	"gemma2-9b-it":            {0.20, 0.20},
+}
+
+// freeProviders run locally and cost nothing per token.
+var freeProviders = map[string]bool{"Ollama": true}
+
+// lookup finds the price of model, falling back to the longest listed name
+// it starts with so dated versions such as gpt-4o-2024-08-06 are covered.
+func lookup(prices map[string]Price, provider, model string) (Price, bool) {
+	if freeProviders[provider] {
+		return Price{}, true
+	}
+	if p, ok := prices[model]; ok {
+		return p, true
+	}
+	best := ""
+	for name := range prices {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return Price{}, false
+	}
+	return prices[best], true
+}
+
+// Cost returns what input and output tokens cost at price.
+func (p Price) Cost(input, output int) float64 {
+	return (float64(input)*p.Input + float64(output)*p.Output) / 1e6
+}
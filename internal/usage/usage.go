@@ -0,0 +1,279 @@
+package usage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var usagePath string
+
+func init() {
+	home, _ := os.UserHomeDir()
+	usagePath = filepath.Join(home, ".nlcli", "usage.json")
+}
+
+const dateFormat = "2006-01-02"
+
+// Totals add up the requests made to one provider/model.
+type Totals struct {
+	Requests     int     `json:"requests"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+	// Unpriced counts requests to models missing from the price table,
+	// whose cost is not included.
+	Unpriced int `json:"unpriced,omitempty"`
+}
+
+func (t *Totals) add(o Totals) {
+	t.Requests += o.Requests
+	t.InputTokens += o.InputTokens
+	t.OutputTokens += o.OutputTokens
+	t.Cost += o.Cost
+	t.Unpriced += o.Unpriced
+}
+
+// Table maps "Provider/model" to its totals.
+type Table map[string]Totals
+
+// Sum adds up every row of the table.
+func (t Table) Sum() Totals {
+	var sum Totals
+	for _, row := range t {
+		sum.add(row)
+	}
+	return sum
+}
+
+// Keys returns the rows sorted by name.
+func (t Table) Keys() []string {
+	keys := make([]string, 0, len(t))
+	for key := range t {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// file is usage.json: per day, per provider/model totals, and the times of
+// the requests made in the last minute.
+type file struct {
+	Days   map[string]Table `json:"days"`
+	Recent []time.Time      `json:"recent,omitempty"`
+}
+
+// Ledger records token usage and cost for this session and, in
+// ~/.nlcli/usage.json, per day.
+type Ledger struct {
+	mu      sync.Mutex
+	prices  map[string]Price
+	session Table
+	now     func() time.Time
+	err     error
+}
+
+func Path() string {
+	return usagePath
+}
+
+func NewLedger(prices map[string]Price) *Ledger {
+	return &Ledger{prices: prices, session: make(Table), now: time.Now}
+}
+
+// Add records one request and returns its cost, and whether the model has a
+// price.
+func (l *Ledger) Add(provider, model string, input, output int) (float64, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	row := Totals{Requests: 1, InputTokens: int64(input), OutputTokens: int64(output)}
+	price, ok := lookup(l.prices, provider, model)
+	if ok {
+		row.Cost = price.Cost(input, output)
+	} else {
+		row.Unpriced = 1
+	}
+
+	key := provider + "/" + model
+	t := l.session[key]
+	t.add(row)
+	l.session[key] = t
+
+	// read the file again under the lock so several sessions running at
+	// once all count
+	unlock, err := lockUsage()
+	if err != nil {
+		l.err = err
+		return row.Cost, ok, err
+	}
+	defer unlock()
+	f, err := readFile()
+	if err != nil {
+		l.err = err
+		return row.Cost, ok, err
+	}
+	now := l.now()
+	recent := []time.Time{now}
+	for _, t := range f.Recent {
+		if now.Sub(t) < time.Minute {
+			recent = append(recent, t)
+		}
+	}
+	f.Recent = recent
+
+	day := now.Format(dateFormat)
+	if f.Days[day] == nil {
+		f.Days[day] = make(Table)
+	}
+	t = f.Days[day][key]
+	t.add(row)
+	f.Days[day][key] = t
+	if err := writeFile(f); err != nil {
+		l.err = err
+		return row.Cost, ok, err
+	}
+	return row.Cost, ok, nil
+}
+
+// Err returns the last error saving usage.json, once.
+func (l *Ledger) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	err := l.err
+	l.err = nil
+	return err
+}
+
+func (l *Ledger) Session() Table {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	table := make(Table, len(l.session))
+	for key, row := range l.session {
+		table[key] = row
+	}
+	return table
+}
+
+// Since adds up the days from from (inclusive) to today.
+func (l *Ledger) Since(from time.Time) (Table, error) {
+	f, err := readFile()
+	if err != nil {
+		return nil, err
+	}
+	first := from.Format(dateFormat)
+	table := make(Table)
+	for day, rows := range f.Days {
+		if day < first {
+			continue
+		}
+		for key, row := range rows {
+			t := table[key]
+			t.add(row)
+			table[key] = t
+		}
+	}
+	return table, nil
+}
+
+// Days returns the total of each recorded day, oldest first.
+func (l *Ledger) Days() ([]string, []Totals, error) {
+	f, err := readFile()
+	if err != nil {
+		return nil, nil, err
+	}
+	days := make([]string, 0, len(f.Days))
+	for day := range f.Days {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	totals := make([]Totals, len(days))
+	for i, day := range days {
+		totals[i] = f.Days[day].Sum()
+	}
+	return days, totals, nil
+}
+
+// Today returns the start of the current day.
+func (l *Ledger) Today() time.Time {
+	y, m, d := l.now().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+// ThisMonth returns the start of the current month.
+func (l *Ledger) ThisMonth() time.Time {
+	y, m, _ := l.now().Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.Local)
+}
+
+// SplitKey splits a table key into provider and model.
+func SplitKey(key string) (provider, model string) {
+	provider, model, _ = strings.Cut(key, "/")
+	return provider, model
+}
+
+func readFile() (*file, error) {
+	f := &file{Days: make(map[string]Table)}
+	data, err := os.ReadFile(usagePath)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, err
+	}
+	if f.Days == nil {
+		f.Days = make(map[string]Table)
+	}
+	return f, nil
+}
+
+// lockUsage holds an exclusive lock on usage.json.lock until the returned
+// function is called. The lock is on a separate file because usage.json
+// itself is replaced on every write.
+func lockUsage() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(usagePath), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(usagePath+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+func writeFile(f *file) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(usagePath), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(usagePath), ".usage-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), usagePath)
+}
@@ -0,0 +1,143 @@
+package usage
+
+import (
+	"math"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		provider, model string
+		want            Price
+		ok              bool
+	}{
+		{"OpenAI", "gpt-4o", Price{2.50, 10.00}, true},
+		{"OpenAI", "gpt-4o-mini-2024-07-18", Price{0.15, 0.60}, true},
+		{"Anthropic", "claude-sonnet-4-20250514", Price{3.00, 15.00}, true},
+		{"Ollama", "llama3.2", Price{}, true},
+		{"Groq", "some-new-model", Price{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := lookup(DefaultPrices, tt.provider, tt.model)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("lookup(%s, %s) = %v, %v; want %v, %v", tt.provider, tt.model, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLedger(t *testing.T) {
+	old := usagePath
+	usagePath = filepath.Join(t.TempDir(), "usage.json")
+	defer func() { usagePath = old }()
+
+	day := time.Date(2026, 3, 31, 12, 0, 0, 0, time.Local)
+	l := NewLedger(DefaultPrices)
+	l.now = func() time.Time { return day }
+
+	if cost, ok, err := l.Add("OpenAI", "gpt-4o", 1000, 100); err != nil || !ok || math.Abs(cost-0.0035) > 1e-9 {
+		t.Fatalf("Add() = %v, %v, %v; want $0.0035", cost, ok, err)
+	}
+	l.Add("Groq", "unknown-model", 500, 50)
+	day = day.AddDate(0, 0, 1)
+	l.Add("OpenAI", "gpt-4o", 1000, 100)
+
+	session := l.Session().Sum()
+	if session.Requests != 3 || session.InputTokens != 2500 || session.Unpriced != 1 {
+		t.Errorf("session = %+v", session)
+	}
+
+	// a new ledger, as in the next session, reads the days back
+	next := NewLedger(DefaultPrices)
+	next.now = l.now
+	today, err := next.Since(next.Today())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := today["OpenAI/gpt-4o"]; got.Requests != 1 || got.OutputTokens != 100 {
+		t.Errorf("today = %+v", today)
+	}
+	month, _ := next.Since(next.ThisMonth())
+	if month.Sum().Requests != 1 {
+		t.Errorf("April has %d requests, want 1", month.Sum().Requests)
+	}
+	days, totals, _ := next.Days()
+	if len(days) != 2 || days[0] != "2026-03-31" || totals[0].Requests != 2 {
+		t.Errorf("Days() = %v, %+v", days, totals)
+	}
+}
+
+func TestLedgerConcurrentSessions(t *testing.T) {
+	old := usagePath
+	usagePath = filepath.Join(t.TempDir(), "usage.json")
+	defer func() { usagePath = old }()
+
+	// each ledger stands for a separate session writing the same file
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l := NewLedger(DefaultPrices)
+			for range 20 {
+				if _, _, err := l.Add("OpenAI", "gpt-4o", 10, 1); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	l := NewLedger(DefaultPrices)
+	today, err := l.Since(l.Today())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := today.Sum().Requests; got != 160 {
+		t.Errorf("recorded %d requests, want 160", got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	old := usagePath
+	usagePath = filepath.Join(t.TempDir(), "usage.json")
+	defer func() { usagePath = old }()
+
+	now := time.Date(2026, 5, 20, 9, 0, 0, 0, time.Local)
+	l := NewLedger(DefaultPrices)
+	l.now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		l.Add("OpenAI", "gpt-4o", 100000, 10000) // $0.35 each
+	}
+
+	tests := []struct {
+		name        string
+		budget      Budget
+		over        bool
+		rateLimited bool
+	}{
+		{name: "no limits", budget: Budget{}},
+		{name: "daily cost", budget: Budget{DailyCost: 1}, over: true},
+		{name: "daily cost left", budget: Budget{DailyCost: 2}},
+		{name: "monthly tokens", budget: Budget{MonthlyTokens: 300000}, over: true},
+		{name: "rate", budget: Budget{RequestsPerMinute: 3}, over: true, rateLimited: true},
+		{name: "rate left", budget: Budget{RequestsPerMinute: 4}},
+	}
+	for _, tt := range tests {
+		reason, rateLimited, err := l.Check(tt.budget)
+		if err != nil || (reason != "") != tt.over || rateLimited != tt.rateLimited {
+			t.Errorf("%s: Check() = %q, %v, %v", tt.name, reason, rateLimited, err)
+		}
+	}
+
+	now = now.Add(2 * time.Minute)
+	if reason, _, _ := l.Check(Budget{RequestsPerMinute: 3}); reason != "" {
+		t.Errorf("rate limit still applies a minute later: %s", reason)
+	}
+	now = now.AddDate(0, 0, 1)
+	if reason, _, _ := l.Check(Budget{DailyCost: 1}); reason != "" {
+		t.Errorf("daily cost limit still applies the next day: %s", reason)
+	}
+}
@@ -0,0 +1,147 @@
+package vault
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Prompt reads a passphrase from the user without echoing it.
+type Prompt func(label string) (string, error)
+
+var (
+	sessionMu sync.Mutex
+	session   *Vault
+)
+
+// KeyFilePath returns where a key file is read from: $NLCLI_VAULT_KEY_FILE, or
+// nlcli-vault.key in $XDG_RUNTIME_DIR. It is never next to the vault in
+// ~/.nlcli, where backups would carry both. Without either variable there is
+// no key file and the path is empty.
+func KeyFilePath() string {
+	if path := os.Getenv(KeyFileEnv); path != "" {
+		return path
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "nlcli-vault.key")
+	}
+	return ""
+}
+
+// keyFilePassphrase returns the key file contents, if there is one. A key file
+// stands in for the passphrase on headless machines.
+func keyFilePassphrase() (string, bool, error) {
+	if KeyFilePath() == "" {
+		return "", false, nil
+	}
+	data, err := os.ReadFile(KeyFilePath())
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	pass := strings.TrimSpace(string(data))
+	if pass == "" {
+		return "", false, fmt.Errorf("key file %s is empty", KeyFilePath())
+	}
+	return pass, true, nil
+}
+
+// Unlock returns the vault for this session, unlocking it on first use with
+// the key file or, without one, a passphrase read through prompt. A new
+// vault asks for its passphrase twice.
+func Unlock(prompt Prompt) (*Vault, error) {
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+	if session != nil {
+		return session, nil
+	}
+
+	pass, ok, err := keyFilePassphrase()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if pass, err = askPassphrase(prompt); err != nil {
+			return nil, err
+		}
+	}
+
+	v, err := Open(pass)
+	if err != nil {
+		return nil, err
+	}
+	session = v
+	return v, nil
+}
+
+func askPassphrase(prompt Prompt) (string, error) {
+	if prompt == nil {
+		if KeyFilePath() == "" {
+			return "", fmt.Errorf("vault is locked; set %s to a key file to open it without a passphrase", KeyFileEnv)
+		}
+		return "", fmt.Errorf("vault is locked and no key file was found at %s", KeyFilePath())
+	}
+	if Exists() {
+		return prompt("Vault passphrase: ")
+	}
+
+	fmt.Println("Creating an encrypted vault at " + vaultPath)
+	pass, err := prompt("New vault passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	again, err := prompt("Repeat passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if pass == "" || pass != again {
+		return "", fmt.Errorf("passphrases are empty or do not match")
+	}
+	return pass, nil
+}
+
+// Lock forgets the unlocked vault, so the next use asks again.
+func Lock() {
+	sessionMu.Lock()
+	session = nil
+	sessionMu.Unlock()
+}
+
+// CreateKeyFile writes a random key file and lets it unlock v as well, so the
+// vault opens without a passphrase on this machine. The passphrase keeps
+// working, so losing the key file loses nothing.
+func CreateKeyFile(v *Vault) (string, error) {
+	path := KeyFilePath()
+	if path == "" {
+		return "", fmt.Errorf("set %s to where the key file should go, outside ~/.nlcli", KeyFileEnv)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("%s already exists", path)
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	pass := base64.StdEncoding.EncodeToString(raw)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(pass+"\n"), 0600); err != nil {
+		return "", err
+	}
+	if err := v.AddKeyFile(pass); err != nil {
+		return "", err
+	}
+	if err := v.Save(); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
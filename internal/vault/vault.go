@@ -0,0 +1,350 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const iterations = 600000
+
+// KeyFileEnv sets where the key file is looked for.
+const KeyFileEnv = "NLCLI_VAULT_KEY_FILE"
+
+var vaultPath string
+
+func init() {
+	home, _ := os.UserHomeDir()
+	vaultPath = filepath.Join(home, ".nlcli", "vault.json")
+}
+
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted vault")
+
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// file is the on-disk form. Names are kept in the clear so the prompt can
+// mention them without unlocking, and are authenticated as additional data
+// so they cannot be changed without the passphrase. From version 3 the data
+// is encrypted with a random key, stored once per way to unlock it in Keys;
+// versions 1 and 2 derive the data key from the passphrase with Salt.
+type file struct {
+	Version    int          `json:"version"`
+	Iterations int          `json:"iterations,omitempty"`
+	Salt       []byte       `json:"salt,omitempty"`
+	Keys       []wrappedKey `json:"keys,omitempty"`
+	Nonce      []byte       `json:"nonce"`
+	Names      []string     `json:"names"`
+	Data       []byte       `json:"data"`
+}
+
+// Kinds of wrappedKey.
+const (
+	keyPassphrase = "passphrase"
+	keyFile       = "keyfile"
+)
+
+// wrappedKey is the data key encrypted with a key derived from a passphrase
+// or from the contents of a key file.
+type wrappedKey struct {
+	Kind       string `json:"kind"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Data       []byte `json:"data"`
+}
+
+// payload is what gets encrypted. Secrets can be referenced from commands
+// as {{secret:NAME}}; credentials (API keys) never can.
+type payload struct {
+	Secrets     map[string]string `json:"secrets"`
+	Credentials map[string]string `json:"credentials"`
+}
+
+// Vault is an unlocked secret store.
+type Vault struct {
+	key         []byte
+	keys        []wrappedKey
+	secrets     map[string]string
+	credentials map[string]string
+}
+
+func Path() string {
+	return vaultPath
+}
+
+func Exists() bool {
+	_, err := os.Stat(vaultPath)
+	return err == nil
+}
+
+// Names lists the stored secret names without unlocking the vault.
+func Names() ([]string, error) {
+	f, err := readFile()
+	if err != nil || f == nil {
+		return nil, err
+	}
+	return f.Names, nil
+}
+
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Open unlocks the vault with passphrase, which may also be the contents of
+// a key file, or starts an empty one when none exists yet. Nothing is
+// written until Save.
+func Open(passphrase string) (*Vault, error) {
+	f, err := readFile()
+	if err != nil {
+		return nil, err
+	}
+	if f == nil {
+		v := &Vault{key: make([]byte, 32), secrets: make(map[string]string), credentials: make(map[string]string)}
+		if _, err := rand.Read(v.key); err != nil {
+			return nil, err
+		}
+		return v, v.Rekey(passphrase)
+	}
+
+	var key []byte
+	keys := f.Keys
+	if f.Version < 3 {
+		if key, err = deriveKey(passphrase, f.Salt, f.Iterations); err != nil {
+			return nil, err
+		}
+	} else if key, err = unwrapKey(f.Keys, passphrase); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, f.Nonce, f.Data, additionalData(f.Names))
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	var p payload
+	if f.Version == 1 {
+		err = json.Unmarshal(plain, &p.Secrets)
+	} else {
+		err = json.Unmarshal(plain, &p)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("corrupt vault: %w", err)
+	}
+	v := &Vault{key: key, keys: keys, secrets: p.Secrets, credentials: p.Credentials}
+	if v.secrets == nil {
+		v.secrets = make(map[string]string)
+	}
+	if v.credentials == nil {
+		v.credentials = make(map[string]string)
+	}
+	if f.Version < 3 {
+		// the old passphrase-derived key becomes the data key
+		return v, v.Rekey(passphrase)
+	}
+	return v, nil
+}
+
+// Rekey switches the vault to a new passphrase. A key file keeps working.
+// It takes effect on Save.
+func (v *Vault) Rekey(passphrase string) error {
+	return v.wrap(keyPassphrase, passphrase)
+}
+
+// AddKeyFile lets the contents of a key file unlock the vault besides the
+// passphrase, replacing any earlier key file. It takes effect on Save.
+func (v *Vault) AddKeyFile(contents string) error {
+	return v.wrap(keyFile, contents)
+}
+
+// wrap stores the data key encrypted with secret as the only key of kind.
+func (v *Vault) wrap(kind, secret string) error {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	kek, err := deriveKey(secret, salt, iterations)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(kek)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	w := wrappedKey{Kind: kind, Iterations: iterations, Salt: salt, Nonce: nonce,
+		Data: gcm.Seal(nil, nonce, v.key, []byte(kind))}
+
+	keys := []wrappedKey{w}
+	for _, k := range v.keys {
+		if k.Kind != kind {
+			keys = append(keys, k)
+		}
+	}
+	v.keys = keys
+	return nil
+}
+
+// unwrapKey returns the data key from the first of keys that secret opens.
+func unwrapKey(keys []wrappedKey, secret string) ([]byte, error) {
+	for _, k := range keys {
+		kek, err := deriveKey(secret, k.Salt, k.Iterations)
+		if err != nil {
+			return nil, err
+		}
+		gcm, err := newGCM(kek)
+		if err != nil {
+			return nil, err
+		}
+		if key, err := gcm.Open(nil, k.Nonce, k.Data, []byte(k.Kind)); err == nil {
+			return key, nil
+		}
+	}
+	return nil, ErrWrongPassphrase
+}
+
+// HasKeyFile reports whether a key file can unlock the vault.
+func (v *Vault) HasKeyFile() bool {
+	for _, k := range v.keys {
+		if k.Kind == keyFile {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Vault) Get(name string) (string, bool) {
+	value, ok := v.secrets[name]
+	return value, ok
+}
+
+func (v *Vault) Set(name, value string) error {
+	if !ValidName(name) {
+		return fmt.Errorf("invalid secret name %q (use letters, digits and _)", name)
+	}
+	v.secrets[name] = value
+	return nil
+}
+
+func (v *Vault) Delete(name string) bool {
+	_, ok := v.secrets[name]
+	delete(v.secrets, name)
+	return ok
+}
+
+func (v *Vault) Credential(name string) (string, bool) {
+	value, ok := v.credentials[name]
+	return value, ok
+}
+
+func (v *Vault) SetCredential(name, value string) {
+	v.credentials[name] = value
+}
+
+func (v *Vault) DeleteCredential(name string) {
+	delete(v.credentials, name)
+}
+
+func (v *Vault) CredentialNames() []string {
+	names := make([]string, 0, len(v.credentials))
+	for name := range v.credentials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (v *Vault) Names() []string {
+	names := make([]string, 0, len(v.secrets))
+	for name := range v.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save encrypts the secrets under a fresh nonce and writes the vault.
+func (v *Vault) Save() error {
+	plain, err := json.Marshal(payload{Secrets: v.secrets, Credentials: v.credentials})
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(v.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	names := v.Names()
+	f := file{
+		Version: 3,
+		Keys:    v.keys,
+		Nonce:   nonce,
+		Names:   names,
+		Data:    gcm.Seal(nil, nonce, plain, additionalData(names)),
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(vaultPath), 0700); err != nil {
+		return err
+	}
+	tmp := vaultPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, vaultPath)
+}
+
+func readFile() (*file, error) {
+	data, err := os.ReadFile(vaultPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("corrupt vault: %w", err)
+	}
+	if f.Version < 1 || f.Version > 3 {
+		return nil, fmt.Errorf("unsupported vault version %d", f.Version)
+	}
+	return &f, nil
+}
+
+func deriveKey(passphrase string, salt []byte, iter int) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, passphrase, salt, iter, 32)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func additionalData(names []string) []byte {
+	return []byte(strings.Join(names, "\n"))
+}
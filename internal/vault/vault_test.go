@@ -0,0 +1,122 @@
+package vault
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestVault(t *testing.T) {
+	vaultPath = filepath.Join(t.TempDir(), "vault.json")
+
+	v, err := Open("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Set("GITHUB_TOKEN", "ghp_example"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Set("bad name", "x"); err == nil {
+		t.Error("Set() accepted an invalid name")
+	}
+	if err := v.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if names, err := Names(); err != nil || !slices.Equal(names, []string{"GITHUB_TOKEN"}) {
+		t.Errorf("Names() = %v, %v", names, err)
+	}
+	if _, err := Open("wrong"); err != ErrWrongPassphrase {
+		t.Errorf("Open(wrong) error = %v, want ErrWrongPassphrase", err)
+	}
+
+	v, err = Open("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := v.Get("GITHUB_TOKEN"); !ok || got != "ghp_example" {
+		t.Errorf("Get() = %q, %v", got, ok)
+	}
+}
+
+func TestUnlockWithKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	vaultPath = filepath.Join(dir, "vault.json")
+	t.Setenv(KeyFileEnv, filepath.Join(dir, "vault.key"))
+	defer Lock()
+
+	prompt := func(string) (string, error) { return "passphrase", nil }
+	v, err := Unlock(prompt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.SetCredential("API_KEY", "sk-test")
+	if _, err := CreateKeyFile(v); err != nil {
+		t.Fatal(err)
+	}
+
+	Lock()
+	if _, err := Unlock(nil); err != nil {
+		t.Fatalf("Unlock() with key file error = %v", err)
+	}
+	// the key file is an extra way in, so losing it loses nothing
+	os.Remove(KeyFilePath())
+	v, err = Open("passphrase")
+	if err != nil {
+		t.Fatalf("passphrase no longer opens the vault: %v", err)
+	}
+	if !v.HasKeyFile() {
+		t.Error("HasKeyFile() = false after CreateKeyFile")
+	}
+	if _, err := Open("wrong"); err != ErrWrongPassphrase {
+		t.Errorf("Open(wrong) error = %v, want ErrWrongPassphrase", err)
+	}
+	if _, err := CreateKeyFile(v); err != nil {
+		t.Fatal(err)
+	}
+
+	Lock()
+	v, err = Unlock(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key, ok := v.Credential("API_KEY"); !ok || key != "sk-test" {
+		t.Errorf("Credential() = %q, %v", key, ok)
+	}
+	if len(v.Names()) != 0 {
+		t.Errorf("credentials leaked into secret names: %v", v.Names())
+	}
+}
+
+func TestOpenVersion2(t *testing.T) {
+	vaultPath = filepath.Join(t.TempDir(), "vault.json")
+
+	// a vault written before the data key was wrapped
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	key, _ := deriveKey("old pass", salt, 1000)
+	gcm, _ := newGCM(key)
+	nonce := make([]byte, gcm.NonceSize())
+	plain, _ := json.Marshal(payload{Secrets: map[string]string{"TOKEN": "t0k"}})
+	data, _ := json.Marshal(file{Version: 2, Iterations: 1000, Salt: salt, Nonce: nonce,
+		Names: []string{"TOKEN"}, Data: gcm.Seal(nil, nonce, plain, additionalData([]string{"TOKEN"}))})
+	os.WriteFile(vaultPath, data, 0600)
+
+	v, err := Open("old pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Save(); err != nil {
+		t.Fatal(err)
+	}
+	v, err = Open("old pass")
+	if err != nil {
+		t.Fatalf("Open() after upgrade error = %v", err)
+	}
+	if got, _ := v.Get("TOKEN"); got != "t0k" {
+		t.Errorf("Get(TOKEN) = %q after upgrade", got)
+	}
+}